@@ -9,6 +9,7 @@ type app struct {
 	ServiceName string
 	IsDebug     bool
 	Ip          string
+	Forms       *KForms
 	dbs         map[string]*sqlx.DB
 }
 
@@ -29,6 +30,8 @@ func GetApp() *app {
 		_app = &app{
 			ServiceName: serviceName,
 			IsDebug:     true,
+			Forms:       &KForms{},
+			dbs:         make(map[string]*sqlx.DB),
 		}
 	})
 
@@ -1,7 +1,9 @@
 package kweb
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -20,7 +22,7 @@ func (t *app) PrepareBaseCmd(cmd *cobra.Command, envPrefix, defaultHome string)
 	cmd.PersistentFlags().StringP(HomeFlag, "", defaultHome, "directory for config and data")
 	cmd.PersistentFlags().Bool(TraceFlag, false, "print out full stack trace on errors.toml")
 	cmd.PersistentPreRunE = concatCobraCmdFuncs(bindFlagsLoadViper, cmd.PersistentPreRunE)
-	return Executor{Command: cmd, Exit: os.Exit}
+	return Executor{Command: cmd, Exit: os.Exit, Stderr: os.Stderr}
 }
 
 // initEnv sets to use ENV variables if set.
@@ -53,7 +55,8 @@ func copyEnvVars(prefix string) {
 // Executor wraps the cobra Command with a nicer Execute method
 type Executor struct {
 	*cobra.Command
-	Exit func(int) // this is os.Exit by default, override in tests
+	Exit   func(int) // this is os.Exit by default, override in tests
+	Stderr io.Writer // this is os.Stderr by default, override in tests
 }
 
 type ExitCoder interface {
@@ -67,15 +70,21 @@ func (e Executor) Execute() error {
 	e.SilenceErrors = true
 	err := e.Command.Execute()
 	if err != nil {
+		w := e.Stderr
+		if w == nil {
+			w = os.Stderr
+		}
+
+		fmt.Fprintf(w, "ERROR: %v\n", err)
 		if viper.GetBool(TraceFlag) {
-			fmt.Fprintf(os.Stderr, "ERROR: %+v\n", err)
-		} else {
-			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			traceError(w, err)
 		}
 
 		// return error code 1 by default, can override it with a special error type
+		// anywhere in the wrapped chain, e.g. a *KError
 		exitCode := 1
-		if ec, ok := err.(ExitCoder); ok {
+		var ec ExitCoder
+		if errors.As(err, &ec) {
 			exitCode = ec.ExitCode()
 		}
 		e.Exit(exitCode)
@@ -83,6 +92,18 @@ func (e Executor) Execute() error {
 	return err
 }
 
+// traceError prints every link of the wrapped chain with %+v, fmt.Errorf links only
+// print their message so the walk goes on down to the *KError, whose %+v carries
+// the stack and the rest of its cause chain
+func traceError(w io.Writer, err error) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		fmt.Fprintf(w, "  %+v\n", err)
+		if _, ok := err.(*KError); ok {
+			return
+		}
+	}
+}
+
 type cobraCmdFunc func(cmd *cobra.Command, args []string) error
 
 // Returns a single function that calls each argument function in sequence
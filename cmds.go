@@ -1,21 +1,234 @@
 package kweb
 
 import (
+	"fmt"
 	"github.com/spf13/cobra"
+	"io/ioutil"
+	"os"
 )
 
 var GenErrorsCmd = &cobra.Command{
 	Use:     "errs",
 	Aliases: []string{"err"},
-	Short:   "Show version info",
-	Run: func(cmd *cobra.Command, args []string) {
+	Short:   "错误目录工具",
+}
+
+var genErrorsGenCmd = &cobra.Command{
+	Use:   "gen",
+	Short: "根据errors.toml生成带类型的错误构造函数",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_in, _ := cmd.Flags().GetString("in")
+		_out, _ := cmd.Flags().GetString("out")
+		_pkg, _ := cmd.Flags().GetString("pkg")
+
+		var errs KErrors
+		if err := loadKErrors(&errs, _in); err != nil {
+			return err
+		}
+
+		_src, err := GenErrors(&errs, _pkg)
+		if err != nil {
+			return err
+		}
+		return writeOut(cmd, _out, _src)
+	},
+}
+
+var genErrorsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "导出错误目录, 格式支持json, markdown, ts",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_in, _ := cmd.Flags().GetString("in")
+		_out, _ := cmd.Flags().GetString("out")
+		_format, _ := cmd.Flags().GetString("format")
+
+		var errs KErrors
+		if err := loadKErrors(&errs, _in); err != nil {
+			return err
+		}
+
+		_dt, err := ExportErrors(&errs, _format)
+		if err != nil {
+			return err
+		}
+		return writeOut(cmd, _out, _dt)
+	},
+}
+
+var genErrorsNextCmd = &cobra.Command{
+	Use:   "next <ns>",
+	Short: "输出namespace范围内下一个可用的code",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_in, _ := cmd.Flags().GetString("in")
+
+		var errs KErrors
+		if err := loadKErrors(&errs, _in); err != nil {
+			return err
+		}
+
+		_code, err := errs.NextCode(args[0])
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(cmd.OutOrStdout(), _code)
+		return err
 	},
 }
 
 var GenFormCmd = &cobra.Command{
-	Use:     "errs",
-	Aliases: []string{"err"},
-	Short:   "Show version info",
-	Run: func(cmd *cobra.Command, args []string) {
+	Use:   "form",
+	Short: "表单规则工具",
+}
+
+var genFormLintCmd = &cobra.Command{
+	Use:   "lint <dir>",
+	Short: "检查表单规则: 语法, 函数和参数类型, 结果类型, 重复和被覆盖的规则",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var forms KForms
+		return loadKForms(&forms, args[0])
 	},
 }
+
+var genFormSchemaCmd = &cobra.Command{
+	Use:   "schema <dir>",
+	Short: "根据表单规则生成JSON Schema(draft 2020-12)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_form, _ := cmd.Flags().GetString("form")
+		_out, _ := cmd.Flags().GetString("out")
+
+		var forms KForms
+		if err := loadKForms(&forms, args[0]); err != nil {
+			return err
+		}
+
+		_dt, err := ExportFormSchema(&forms, _form)
+		if err != nil {
+			return err
+		}
+		return writeOut(cmd, _out, _dt)
+	},
+}
+
+var genFormImportCmd = &cobra.Command{
+	Use:   "import <schema.json>",
+	Short: "把JSON Schema转换成表单规则",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_form, _ := cmd.Flags().GetString("form")
+		_out, _ := cmd.Flags().GetString("out")
+
+		_src, err := ioutil.ReadFile(args[0])
+		if err != nil {
+			return err
+		}
+
+		_dt, err := ImportFormSchema(_src, _form)
+		if err != nil {
+			return err
+		}
+		return writeOut(cmd, _out, _dt)
+	},
+}
+
+var genFormJSCmd = &cobra.Command{
+	Use:   "gen-js <dir>",
+	Short: "根据表单规则生成TypeScript客户端校验模块, 不能转换的规则只在服务端执行",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		_form, _ := cmd.Flags().GetString("form")
+		_out, _ := cmd.Flags().GetString("out")
+
+		var forms KForms
+		if err := loadKForms(&forms, args[0]); err != nil {
+			return err
+		}
+
+		_dt, _ps, err := GenFormsTS(&forms, _form)
+		if err != nil {
+			return err
+		}
+
+		for _, _p := range _ps {
+			fmt.Fprintln(os.Stderr, _p.Error())
+		}
+		return writeOut(cmd, _out, _dt)
+	},
+}
+
+func init() {
+	genErrorsGenCmd.Flags().String("in", "errors.toml", "错误目录文件或者目录")
+	genErrorsGenCmd.Flags().String("out", "errors_gen.go", "生成的Go文件, -表示输出到stdout")
+	genErrorsGenCmd.Flags().String("pkg", "errs", "生成的Go文件的包名")
+
+	genErrorsExportCmd.Flags().String("in", "errors.toml", "错误目录文件或者目录")
+	genErrorsExportCmd.Flags().String("out", "-", "导出的文件, -表示输出到stdout")
+	genErrorsExportCmd.Flags().String("format", "json", "导出格式: json, markdown, ts")
+
+	genErrorsNextCmd.Flags().String("in", "errors.toml", "错误目录文件或者目录")
+
+	GenErrorsCmd.AddCommand(genErrorsGenCmd, genErrorsExportCmd, genErrorsNextCmd)
+
+	genFormSchemaCmd.Flags().String("form", "", "表单名, 为空时导出全部表单")
+	genFormSchemaCmd.Flags().String("out", "-", "导出的文件, -表示输出到stdout")
+
+	genFormImportCmd.Flags().String("form", "", "表单名, 为空时使用title或者$defs中的名字")
+	genFormImportCmd.Flags().String("out", "-", "生成的toml文件, -表示输出到stdout")
+
+	genFormJSCmd.Flags().String("form", "", "表单名, 为空时生成全部表单")
+	genFormJSCmd.Flags().String("out", "forms_gen.ts", "生成的TypeScript文件, -表示输出到stdout")
+
+	GenFormCmd.AddCommand(genFormLintCmd, genFormSchemaCmd, genFormImportCmd, genFormJSCmd)
+}
+
+// loadKErrors 加载错误目录文件或者目录, 有问题时输出全部问题并返回错误
+func loadKErrors(errs *KErrors, in string) error {
+	var _ps []*KErrorsProblem
+	if _fi, err := os.Stat(in); err == nil && _fi.IsDir() {
+		_ps = errs.FromDir(in)
+	} else {
+		_ps = append(errs.FromPath(in), errs.Check()...)
+	}
+
+	for _, _p := range _ps {
+		fmt.Fprintln(os.Stderr, _p.Error())
+	}
+
+	if len(_ps) > 0 {
+		return fmt.Errorf("错误目录[%s]有%d个问题", in, len(_ps))
+	}
+	return nil
+}
+
+// loadKForms 加载并检查表单文件或者目录, 有问题时输出全部问题并返回错误
+func loadKForms(forms *KForms, in string) error {
+	var _ps []*KFormsProblem
+	if _fi, err := os.Stat(in); err == nil && _fi.IsDir() {
+		_ps = forms.FromDir(in)
+	} else if _src, err := ioutil.ReadFile(in); err != nil {
+		_ps = []*KFormsProblem{{File: in, Msg: fmt.Sprintf("文件读取失败: %s", err)}}
+	} else {
+		_ps = forms.loadFile(in, _src)
+	}
+	_ps = append(_ps, forms.Check()...)
+
+	for _, _p := range _ps {
+		fmt.Fprintln(os.Stderr, _p.Error())
+	}
+
+	if len(_ps) > 0 {
+		return fmt.Errorf("表单规则[%s]有%d个问题", in, len(_ps))
+	}
+	return nil
+}
+
+// writeOut 写入文件, out为空或者-时输出到stdout
+func writeOut(cmd *cobra.Command, out string, dt []byte) error {
+	if out == "" || out == "-" {
+		_, err := cmd.OutOrStdout().Write(dt)
+		return err
+	}
+	return ioutil.WriteFile(out, dt, 0644)
+}
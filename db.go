@@ -3,6 +3,7 @@ package kweb
 import (
 	"github.com/jmoiron/sqlx"
 	"github.com/kooksee/go-assert"
+	"github.com/kooksee/kweb/internal/g"
 
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/lib/pq"
@@ -15,3 +16,10 @@ func (t *app) InitDb(cfg *DbConfig) {
 	db.SetMaxIdleConns(10)
 	t.dbs[cfg.Name] = db
 }
+
+// DB 名为name的数据库连接
+func (t *app) DB(name string) *sqlx.DB {
+	db, ok := t.dbs[name]
+	g.AssertBool(!ok, "db %s not found", name)
+	return db
+}
@@ -1,8 +1,17 @@
 package kweb
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/kooksee/kweb/internal/g"
+	"github.com/kooksee/kweb/kerrors"
+	"github.com/rs/zerolog/log"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
 )
 
 func (t *app) app() {
@@ -20,3 +29,165 @@ func (t *app) app() {
 	})
 	g.Assert(r.Run())
 }
+
+// AcceptLanguages 按照q值从高到低解析Accept-Language请求头, q值小于等于0的语言表示不接受, 不返回
+func AcceptLanguages(c *gin.Context) []string {
+	return kerrors.AcceptLanguages(c.GetHeader("Accept-Language"))
+}
+
+// ErrorHandler 把handler通过c.Error返回或者panic的*KError渲染成ErrorEnvelope
+// HTTP状态码和日志级别由错误定义的status和level决定, 其他错误按照未知错误处理
+func ErrorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if r := recover(); r != nil {
+				renderKError(c, kErrorOf(r))
+			}
+		}()
+
+		c.Next()
+
+		if _e := c.Errors.Last(); _e != nil {
+			renderKError(c, kErrorOf(_e.Err))
+		}
+	}
+}
+
+func kErrorOf(r interface{}) *KError {
+	err, ok := r.(error)
+	if !ok {
+		err = fmt.Errorf("%v", r)
+	}
+
+	var _e *KError
+	if errors.As(err, &_e) {
+		return _e
+	}
+	return kerrors.Unknown(err)
+}
+
+func renderKError(c *gin.Context, e *KError) {
+	log.WithLevel(e.LogLevel()).
+		Err(e).
+		Str("ns", e.Ns).
+		Str("name", e.Name).
+		Str("path", c.Request.URL.Path).
+		Msg("请求失败")
+
+	if c.Writer.Written() {
+		c.Abort()
+		return
+	}
+	c.AbortWithStatusJSON(e.HTTPStatus(), &ErrorEnvelope{Code: e.Code, Msg: e.Msg})
+}
+
+// FormKey 校验通过的表单数据在gin.Context中的键
+const FormKey = "kweb.form"
+
+// Form 使用GetApp().Forms中名为name的表单校验请求, 参考KForms.Form
+func Form(name string, opts ...ValidateOption) gin.HandlerFunc {
+	return GetApp().Forms.Form(name, opts...)
+}
+
+// Form 校验请求的中间件
+// 依次合并query, body(json, urlencoded, multipart)和路径参数, 同名时后者覆盖前者
+// 校验失败时返回400和ValidationResult, 成功时把转换之后的数据保存到gin.Context, 通过FormOf获取
+// 请求的context传给注册的校验函数, 表单不存在时在注册路由时panic, 表单需要在注册路由之前加载
+func (t *KForms) Form(name string, opts ...ValidateOption) gin.HandlerFunc {
+	_, ok := t.data[name]
+	g.AssertBool(!ok, "表单[%s]不存在", name)
+
+	return func(c *gin.Context) {
+		_dt, err := formInput(c)
+		if err != nil {
+			_res := &ValidationResult{Form: name}
+			_res.add(&ValidationError{Message: fmt.Sprintf("请求解析失败: %s", err)})
+			c.AbortWithStatusJSON(http.StatusBadRequest, _res)
+			return
+		}
+
+		_res := t.Validate(name, _dt, append([]ValidateOption{WithContext(c.Request.Context())}, opts...)...)
+		if !_res.OK() {
+			c.AbortWithStatusJSON(http.StatusBadRequest, _res)
+			return
+		}
+
+		c.Set(FormKey, _res.Doc)
+		c.Next()
+	}
+}
+
+// FormOf 获取Form中间件校验通过的数据, 没有经过校验时为nil
+func FormOf(c *gin.Context) map[string]interface{} {
+	_dt, _ := c.Get(FormKey)
+	_m, _ := _dt.(map[string]interface{})
+	return _m
+}
+
+// BindForm 把Form中间件校验通过的数据解码到out, 类型不匹配时返回400并中止请求
+func BindForm(c *gin.Context, out interface{}) bool {
+	_res := &ValidationResult{Doc: FormOf(c)}
+	if _res.Decode(out) {
+		return true
+	}
+
+	c.AbortWithStatusJSON(http.StatusBadRequest, _res)
+	return false
+}
+
+func formInput(c *gin.Context) (map[string]interface{}, error) {
+	_dt := make(map[string]interface{})
+	mergeFormValues(_dt, c.Request.URL.Query())
+
+	switch c.ContentType() {
+	case gin.MIMEJSON:
+		_raw, err := c.GetRawData()
+		if err != nil {
+			return nil, err
+		}
+
+		// 读取之后放回去, handler还可以再次读取body
+		c.Request.Body = ioutil.NopCloser(bytes.NewReader(_raw))
+		if len(bytes.TrimSpace(_raw)) > 0 {
+			var _body map[string]interface{}
+			if err := g.Json.Unmarshal(_raw, &_body); err != nil {
+				return nil, err
+			}
+			for k, v := range _body {
+				_dt[k] = v
+			}
+		}
+	case gin.MIMEPOSTForm:
+		if err := c.Request.ParseForm(); err != nil {
+			return nil, err
+		}
+		mergeFormValues(_dt, c.Request.PostForm)
+	case gin.MIMEMultipartPOSTForm:
+		_mf, err := c.MultipartForm()
+		if err != nil {
+			return nil, err
+		}
+		mergeFormValues(_dt, _mf.Value)
+	}
+
+	for _, _p := range c.Params {
+		_dt[_p.Key] = _p.Value
+	}
+	return _dt, nil
+}
+
+// mergeFormValues 单个值保存为字符串, 多个值或者键以[]结尾时保存为数组
+func mergeFormValues(dt map[string]interface{}, vs url.Values) {
+	for k, v := range vs {
+		if len(v) == 1 && !strings.HasSuffix(k, "[]") {
+			dt[k] = v[0]
+			continue
+		}
+
+		_a := make([]interface{}, len(v))
+		for i := range v {
+			_a[i] = v[i]
+		}
+		dt[strings.TrimSuffix(k, "[]")] = _a
+	}
+}
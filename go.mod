@@ -1,6 +1,6 @@
 module github.com/kooksee/kweb
 
-go 1.12
+go 1.16
 
 require (
 	github.com/BurntSushi/toml v0.3.1
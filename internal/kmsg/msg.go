@@ -0,0 +1,129 @@
+package kmsg
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+var placeholder = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+
+// Msg 错误信息和表单信息共用的模板
+// 支持命名占位符, 例如 "字段{field}的长度不能超过{max}", 参数可以是map, struct或者按照占位符出现的顺序传入
+// 没有命名占位符的模板按照fmt.Sprintf格式化, 兼容 "错误信息 %s" 这种写法
+type Msg struct {
+	src   string
+	names []string
+}
+
+func Parse(src string) *Msg {
+	_m := &Msg{src: src}
+	for _, _s := range placeholder.FindAllStringSubmatch(src, -1) {
+		if !containsString(_m.names, _s[1]) {
+			_m.names = append(_m.names, _s[1])
+		}
+	}
+	return _m
+}
+
+// String 模板的原文
+func (t *Msg) String() string {
+	return t.src
+}
+
+// Names 模板使用的占位符, 按照第一次出现的顺序
+func (t *Msg) Names() []string {
+	return t.names
+}
+
+// Undeclared 返回模板中使用了, 但是没有在declared中声明的占位符
+func (t *Msg) Undeclared(declared []string) []string {
+	var _ns []string
+	for _, n := range t.names {
+		if !containsString(declared, n) {
+			_ns = append(_ns, n)
+		}
+	}
+	return _ns
+}
+
+func (t *Msg) Render(args ...interface{}) string {
+	if len(t.names) == 0 {
+		if len(args) == 0 {
+			return t.src
+		}
+		return fmt.Sprintf(t.src, args...)
+	}
+
+	return t.RenderNamed(Values(t.names, args))
+}
+
+// RenderNamed 只填充命名占位符, 没有对应值的占位符原样保留
+func (t *Msg) RenderNamed(values map[string]interface{}) string {
+	return placeholder.ReplaceAllStringFunc(t.src, func(s string) string {
+		if _v, ok := values[s[1:len(s)-1]]; ok {
+			return fmt.Sprint(_v)
+		}
+		return s
+	})
+}
+
+// Values 把参数转换成占位符对应的值
+// 只有一个参数并且是map或者struct时按照名字取值, 否则按照names的顺序取值
+func Values(names []string, args []interface{}) map[string]interface{} {
+	_vs := make(map[string]interface{})
+
+	if len(args) == 1 {
+		_v := reflect.Indirect(reflect.ValueOf(args[0]))
+		switch _v.Kind() {
+		case reflect.Map:
+			for _, _k := range _v.MapKeys() {
+				if _k.Kind() == reflect.String {
+					_vs[_k.String()] = _v.MapIndex(_k).Interface()
+				}
+			}
+			return _vs
+		case reflect.Struct:
+			for _, n := range names {
+				if _f, ok := field(_v, n); ok {
+					_vs[n] = _f
+				}
+			}
+			return _vs
+		}
+	}
+
+	for i, n := range names {
+		if i < len(args) {
+			_vs[n] = args[i]
+		}
+	}
+	return _vs
+}
+
+// field 按照json tag或者字段名(忽略大小写)从struct中取值
+func field(v reflect.Value, name string) (interface{}, bool) {
+	_t := v.Type()
+	for i := 0; i < _t.NumField(); i++ {
+		_f := _t.Field(i)
+		if _f.PkgPath != "" {
+			continue
+		}
+
+		_tag := strings.Split(_f.Tag.Get("json"), ",")[0]
+		if _tag == name || strings.EqualFold(_f.Name, name) {
+			return v.Field(i).Interface(), true
+		}
+	}
+	return nil, false
+}
+
+func containsString(ss []string, s string) bool {
+	for _, _s := range ss {
+		if _s == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package ktoml
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// Lines 记录toml文件中每个表和键第一次出现的行号, 键为完整的路径, 例如 user.not_found
+// toml解析库不提供行号, 这里只按行扫描表头和 key = value, 用于问题报告
+type Lines map[string]int
+
+func ParseLines(src []byte) Lines {
+	_ls := make(Lines)
+
+	var _table []string
+	_sc := bufio.NewScanner(bytes.NewReader(src))
+	_sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for _n := 1; _sc.Scan(); _n++ {
+		_line := strings.TrimSpace(_sc.Text())
+
+		switch {
+		case _line == "" || strings.HasPrefix(_line, "#"):
+		case strings.HasPrefix(_line, "[["):
+			_table = nil
+		case strings.HasPrefix(_line, "["):
+			if i := strings.LastIndex(_line, "]"); i > 0 {
+				_table = splitKey(_line[1:i])
+				_ls.add(_table, _n)
+			}
+		default:
+			if i := keyEnd(_line); i > 0 {
+				_ls.add(append(append([]string{}, _table...), splitKey(_line[:i])...), _n)
+			}
+		}
+	}
+	return _ls
+}
+
+func (t Lines) add(key []string, line int) {
+	_k := strings.Join(key, ".")
+	if _, ok := t[_k]; !ok {
+		t[_k] = line
+	}
+}
+
+// Line 键所在的行号, 没有找到时为0
+func (t Lines) Line(key ...string) int {
+	return t[strings.Join(key, ".")]
+}
+
+// keyEnd 返回 key = value 中=的位置, 忽略引号中的=
+func keyEnd(line string) int {
+	var _quote rune
+	for i, r := range line {
+		switch {
+		case _quote != 0:
+			if r == _quote {
+				_quote = 0
+			}
+		case r == '"' || r == '\'':
+			_quote = r
+		case r == '=':
+			return i
+		}
+	}
+	return -1
+}
+
+// splitKey 拆分 a."b.c".d 这种键, 去掉引号
+func splitKey(key string) []string {
+	var _ks []string
+	var _b strings.Builder
+	var _quote rune
+	for _, r := range key {
+		switch {
+		case _quote != 0:
+			if r == _quote {
+				_quote = 0
+			} else {
+				_b.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			_quote = r
+		case r == '.':
+			_ks = append(_ks, strings.TrimSpace(_b.String()))
+			_b.Reset()
+		default:
+			_b.WriteRune(r)
+		}
+	}
+	return append(_ks, strings.TrimSpace(_b.String()))
+}
@@ -403,88 +403,80 @@ func (t *KValidator) IsBitcoinBech32Address() bool {
 }
 
 // ExcludesRune is the validation function for validating that the field's value does not contain the rune specified within the param.
-func (t *KValidator) excludesRune() bool {
-	return !t.ContainsRune()
+func (t *KValidator) ExcludesRune(r string) bool {
+	_s, ok := t.stringValue()
+	if !ok {
+		return false
+	}
+
+	_r, _ := utf8.DecodeRuneInString(r)
+
+	return !strings.ContainsRune(_s, _r)
 }
 
 // ExcludesAll is the validation function for validating that the field's value does not contain any of the characters specified within the param.
-func (t *KValidator) ExcludesAll() bool {
-	return !t.ContainsAny()
+func (t *KValidator) ExcludesAll(chars string) bool {
+	_s, ok := t.stringValue()
+	return ok && !strings.ContainsAny(_s, chars)
 }
 
 // Excludes is the validation function for validating that the field's value does not contain the text specified within the param.
-func (t *KValidator) Excludes() bool {
-	return !t.Contains()
+func (t *KValidator) Excludes(param interface{}) bool {
+	switch _k := t.value().Kind(); _k {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return !t.Contains(param)
+	default:
+		return t.fail("字段类型[%s]不支持Excludes", _k)
+	}
 }
 
 // ContainsRune is the validation function for validating that the field's value contains the rune specified within the param.
-func (t *KValidator) ContainsRune() bool {
+func (t *KValidator) ContainsRune(r string) bool {
+	_s, ok := t.stringValue()
+	if !ok {
+		return false
+	}
 
-	r, _ := utf8.DecodeRuneInString(t.data.String())
+	_r, _ := utf8.DecodeRuneInString(r)
 
-	return strings.ContainsRune(t.data.String(), r)
+	return strings.ContainsRune(_s, _r)
 }
 
 // ContainsAny is the validation function for validating that the field's value contains any of the characters specified within the param.
-func (t *KValidator) ContainsAny() bool {
-	return strings.ContainsAny(t.data.String(), t.data.String())
-}
-
-// Contains is the validation function for validating that the field's value contains the text specified within the param.
-func (t *KValidator) Contains() bool {
-	return strings.Contains(t.data.String(), t.data.String())
+func (t *KValidator) ContainsAny(chars string) bool {
+	_s, ok := t.stringValue()
+	return ok && strings.ContainsAny(_s, chars)
 }
 
 // FieldContains is the validation function for validating if the current field's value contains the field specified by the param's value.
 func (t *KValidator) FieldContains(s string) bool {
-	field := t.data
-	return strings.Contains(field.String(), s)
+	_s, ok := t.stringValue()
+	return ok && strings.Contains(_s, s)
 }
 
 // FieldExcludes is the validation function for validating if the current field's value excludes the field specified by the param's value.
 func (t *KValidator) FieldExcludes(s string) bool {
-	field := t.data
-	return !strings.Contains(field.String(), s)
+	_s, ok := t.stringValue()
+	return ok && !strings.Contains(_s, s)
+}
+
+// stringValue returns the field's string value, or false if it isn't a string.
+func (t *KValidator) stringValue() (string, bool) {
+	_v := t.value()
+	if _v.Kind() != reflect.String {
+		return "", t.fail("字段类型[%s]不是字符串", _v.Kind())
+	}
+	return _v.String(), true
 }
 
 // IsNe is the validation function for validating that the field's value does not equal the provided param value.
-func (t *KValidator) IsNe() bool {
-	return !t.IsEq()
+func (t *KValidator) IsNe(param interface{}) bool {
+	return t.Ne(param)
 }
 
 // IsEq is the validation function for validating if the current field's value is equal to the param's value.
-func (t *KValidator) IsEq() bool {
-
-	field := t.data
-	param := field.String()
-
-	switch field.Kind() {
-
-	case reflect.String:
-		return field.String() == param
-
-	case reflect.Slice, reflect.Map, reflect.Array:
-		p := asInt(param)
-
-		return int64(field.Len()) == p
-
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		p := asInt(param)
-
-		return field.Int() == p
-
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		p := asUint(param)
-
-		return field.Uint() == p
-
-	case reflect.Float32, reflect.Float64:
-		p := asFloat(param)
-
-		return field.Float() == p
-	}
-
-	panic(fmt.Sprintf("Bad field type %T", field.Interface()))
+func (t *KValidator) IsEq(param interface{}) bool {
+	return t.Eq(param)
 }
 
 // IsBase64 is the validation function for validating if the current field's value is a valid base 64.
@@ -626,231 +618,38 @@ func (t *KValidator) Required() bool {
 }
 
 // IsGte is the validation function for validating if the current field's value is greater than or equal to the param's value.
-func (t *KValidator) IsGte() bool {
-
-	field := t.data
-	param := t.data.String()
-
-	switch field.Kind() {
-
-	case reflect.String:
-		p := asInt(param)
-
-		return int64(utf8.RuneCountInString(field.String())) >= p
-
-	case reflect.Slice, reflect.Map, reflect.Array:
-		p := asInt(param)
-
-		return int64(field.Len()) >= p
-
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		p := asInt(param)
-
-		return field.Int() >= p
-
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		p := asUint(param)
-
-		return field.Uint() >= p
-
-	case reflect.Float32, reflect.Float64:
-		p := asFloat(param)
-
-		return field.Float() >= p
-
-	case reflect.Struct:
-
-		if field.Type() == timeType {
-
-			now := time.Now().UTC()
-			t := field.Interface().(time.Time)
-
-			return t.After(now) || t.Equal(now)
-		}
-	}
-
-	panic(fmt.Sprintf("Bad field type %T", field.Interface()))
+func (t *KValidator) IsGte(param interface{}) bool {
+	return t.Gte(param)
 }
 
 // IsGt is the validation function for validating if the current field's value is greater than the param's value.
-func (t *KValidator) IsGt() bool {
-
-	field := t.data
-	param := t.data.String()
-
-	switch field.Kind() {
-
-	case reflect.String:
-		p := asInt(param)
-
-		return int64(utf8.RuneCountInString(field.String())) > p
-
-	case reflect.Slice, reflect.Map, reflect.Array:
-		p := asInt(param)
-
-		return int64(field.Len()) > p
-
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		p := asInt(param)
-
-		return field.Int() > p
-
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		p := asUint(param)
-
-		return field.Uint() > p
-
-	case reflect.Float32, reflect.Float64:
-		p := asFloat(param)
-
-		return field.Float() > p
-	case reflect.Struct:
-
-		if field.Type() == timeType {
-
-			return field.Interface().(time.Time).After(time.Now().UTC())
-		}
-	}
-
-	panic(fmt.Sprintf("Bad field type %T", field.Interface()))
+func (t *KValidator) IsGt(param interface{}) bool {
+	return t.Gt(param)
 }
 
-// HasLengthOf is the validation function for validating if the current field's value is equal to the param's value.
-func (t *KValidator) HasLengthOf() bool {
-
-	field := t.data
-	param := t.data.String()
-
-	switch field.Kind() {
-
-	case reflect.String:
-		p := asInt(param)
-
-		return int64(utf8.RuneCountInString(field.String())) == p
-
-	case reflect.Slice, reflect.Map, reflect.Array:
-		p := asInt(param)
-
-		return int64(field.Len()) == p
-
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		p := asInt(param)
-
-		return field.Int() == p
-
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		p := asUint(param)
-
-		return field.Uint() == p
-
-	case reflect.Float32, reflect.Float64:
-		p := asFloat(param)
-
-		return field.Float() == p
-	}
-
-	panic(fmt.Sprintf("Bad field type %T", field.Interface()))
+// HasLengthOf is the validation function for validating if the length of the current field's value is equal to the param's value.
+func (t *KValidator) HasLengthOf(param interface{}) bool {
+	return t.Len(param)
 }
 
 // HasMinOf is the validation function for validating if the current field's value is greater than or equal to the param's value.
-func (t *KValidator) HasMinOf() bool {
-	return t.IsGte()
+func (t *KValidator) HasMinOf(param interface{}) bool {
+	return t.Gte(param)
 }
 
 // IsLte is the validation function for validating if the current field's value is less than or equal to the param's value.
-func (t *KValidator) IsLte() bool {
-
-	field := t.data
-	param := t.data.String()
-
-	switch field.Kind() {
-
-	case reflect.String:
-		p := asInt(param)
-
-		return int64(utf8.RuneCountInString(field.String())) <= p
-
-	case reflect.Slice, reflect.Map, reflect.Array:
-		p := asInt(param)
-
-		return int64(field.Len()) <= p
-
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		p := asInt(param)
-
-		return field.Int() <= p
-
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		p := asUint(param)
-
-		return field.Uint() <= p
-
-	case reflect.Float32, reflect.Float64:
-		p := asFloat(param)
-
-		return field.Float() <= p
-
-	case reflect.Struct:
-
-		if field.Type() == timeType {
-
-			now := time.Now().UTC()
-			t := field.Interface().(time.Time)
-
-			return t.Before(now) || t.Equal(now)
-		}
-	}
-
-	panic(fmt.Sprintf("Bad field type %T", field.Interface()))
+func (t *KValidator) IsLte(param interface{}) bool {
+	return t.Lte(param)
 }
 
 // IsLt is the validation function for validating if the current field's value is less than the param's value.
-func (t *KValidator) IsLt() bool {
-
-	field := t.data
-	param := t.data.String()
-
-	switch field.Kind() {
-
-	case reflect.String:
-		p := asInt(param)
-
-		return int64(utf8.RuneCountInString(field.String())) < p
-
-	case reflect.Slice, reflect.Map, reflect.Array:
-		p := asInt(param)
-
-		return int64(field.Len()) < p
-
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		p := asInt(param)
-
-		return field.Int() < p
-
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-		p := asUint(param)
-
-		return field.Uint() < p
-
-	case reflect.Float32, reflect.Float64:
-		p := asFloat(param)
-
-		return field.Float() < p
-
-	case reflect.Struct:
-
-		if field.Type() == timeType {
-
-			return field.Interface().(time.Time).Before(time.Now().UTC())
-		}
-	}
-
-	panic(fmt.Sprintf("Bad field type %T", field.Interface()))
+func (t *KValidator) IsLt(param interface{}) bool {
+	return t.Lt(param)
 }
 
 // HasMaxOf is the validation function for validating if the current field's value is less than or equal to the param's value.
-func (t *KValidator) HasMaxOf() bool {
-	return t.IsLte()
+func (t *KValidator) HasMaxOf(param interface{}) bool {
+	return t.Lte(param)
 }
 
 // IsTCP4AddrResolvable is the validation function for validating if the field's value is a resolvable tcp4 address.
@@ -1032,3 +831,103 @@ func (t *KValidator) IsDir() bool {
 
 	panic(fmt.Sprintf("Bad field type %T", t.data.Interface()))
 }
+
+// chinaString returns the field's value with spaces and hyphens removed, or false if it isn't a string.
+func (t *KValidator) chinaString() (string, bool) {
+	_v := t.value()
+	if _v.Kind() != reflect.String {
+		return "", t.fail("字段类型[%s]不是字符串", _v.Kind())
+	}
+	return strings.NewReplacer(" ", "", "-", "").Replace(_v.String()), true
+}
+
+// IsChinaMobile is the validation function for validating if the field's value is a mainland China mobile number,
+// with an optional +86 prefix.
+func (t *KValidator) IsChinaMobile() bool {
+	_v := t.value()
+	return _v.Kind() == reflect.String && chinaMobileRegex.MatchString(_v.String())
+}
+
+// IsChinaIDCard is the validation function for validating if the field's value is an 18-digit resident ID number
+// with a valid birth date and check digit (GB 11643-1999).
+func (t *KValidator) IsChinaIDCard() bool {
+	s, ok := t.chinaString()
+	if !ok || !chinaIDCardRegex.MatchString(s) {
+		return false
+	}
+
+	birth, err := time.Parse("20060102", s[6:14])
+	if err != nil || birth.After(time.Now()) {
+		return t.fail("出生日期[%s]错误", s[6:14])
+	}
+
+	weights := []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	sum := 0
+	for i, w := range weights {
+		sum += int(s[i]-'0') * w
+	}
+
+	if "10X98765432"[sum%11] != strings.ToUpper(s[17:])[0] {
+		return t.fail("校验码错误")
+	}
+	return true
+}
+
+// IsUSCC is the validation function for validating if the field's value is a unified social credit code
+// with a valid check character (GB 32100-2015).
+func (t *KValidator) IsUSCC() bool {
+	s, ok := t.chinaString()
+	if !ok || !uSCCRegex.MatchString(s) {
+		return false
+	}
+
+	const chars = "0123456789ABCDEFGHJKLMNPQRTUWXY"
+	weights := []int{1, 3, 9, 27, 19, 26, 16, 17, 20, 29, 25, 13, 8, 24, 10, 30, 28}
+	sum := 0
+	for i, w := range weights {
+		sum += strings.IndexByte(chars, s[i]) * w
+	}
+
+	if chars[(31-sum%31)%31] != s[17] {
+		return t.fail("校验码错误")
+	}
+	return true
+}
+
+// IsBankCard is the validation function for validating if the field's value is a 12 to 19 digit bank card number
+// passing the Luhn check. Spaces and hyphens are ignored.
+func (t *KValidator) IsBankCard() bool {
+	s, ok := t.chinaString()
+	if !ok || !bankCardRegex.MatchString(s) {
+		return false
+	}
+
+	sum := 0
+	for i := range s {
+		d := int(s[len(s)-1-i] - '0')
+		if i%2 == 1 {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+
+	if sum%10 != 0 {
+		return t.fail("校验码错误")
+	}
+	return true
+}
+
+// IsChinaPostcode is the validation function for validating if the field's value is a mainland China postal code.
+func (t *KValidator) IsChinaPostcode() bool {
+	_v := t.value()
+	return _v.Kind() == reflect.String && chinaPostcodeRegex.MatchString(_v.String())
+}
+
+// IsChinaPlate is the validation function for validating if the field's value is a mainland China plate number,
+// including 8 character new energy plates.
+func (t *KValidator) IsChinaPlate() bool {
+	_v := t.value()
+	return _v.Kind() == reflect.String && chinaPlateRegex.MatchString(_v.String())
+}
@@ -0,0 +1,213 @@
+package validator
+
+import (
+	"fmt"
+	"github.com/antonmedv/expr"
+	"reflect"
+	"regexp"
+	"time"
+)
+
+// 解析之后的检查, 函数是否存在以及参数的类型已经由expr.Parse检查
+// 这里只检查规则结果的类型和字符串字面量参数的值
+
+// kParamChecks 检查字符串字面量参数的值
+var kParamChecks = map[string]func(s string) error{
+	"Match": func(s string) error {
+		_, err := regexp.Compile(s)
+		return err
+	},
+	"Within": func(s string) error {
+		_, err := time.ParseDuration(s)
+		return err
+	},
+	"TZ": func(s string) error {
+		_, err := time.LoadLocation(s)
+		return err
+	},
+	"Field": func(s string) error {
+		_, err := ParsePath(s)
+		return err
+	},
+	"IsType": func(s string) error {
+		if !kJSONTypes[s] {
+			return fmt.Errorf("不是JSON Schema的类型")
+		}
+		return nil
+	},
+	"Before": checkBound,
+	"After":  checkBound,
+}
+
+func checkBound(s string) error {
+	if _, ok := (&KValidator{}).bound(s); !ok {
+		return fmt.Errorf("不是时间")
+	}
+	return nil
+}
+
+// kBoolOps 结果为bool的运算符
+var kBoolOps = map[string]bool{
+	"==": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true, "and": true, "or": true, "&&": true, "||": true,
+	"in": true, "not in": true, "matches": true, "contains": true, "startsWith": true, "endsWith": true,
+}
+
+// Check 检查解析之后的规则, 结果必须是bool, 字符串字面量参数的值必须有效
+// doc为true时是文档规则, 标识符和函数来自文档, 类型不能确定
+func Check(node expr.Node, doc bool) error {
+	_c := &checker{funcs: make(map[string]reflect.Type)}
+	if !doc {
+		for k, v := range Env(&KValidator{}) {
+			_c.funcs[k] = reflect.TypeOf(v)
+		}
+	}
+
+	_n := nodeValue(reflect.ValueOf(node))
+	if !_n.IsValid() {
+		return nil
+	}
+	_c.pkg = _n.Type().PkgPath()
+
+	if err := _c.literals(_n); err != nil {
+		return err
+	}
+	if _kind := _c.kind(_n); _kind != "" && _kind != "bool" {
+		return fmt.Errorf("规则结果的类型是%s, 应该是bool", _kind)
+	}
+	return nil
+}
+
+// checker 按照expr的语法树节点的类型名检查, 节点的字段按照类型取: 第一个字符串字段为运算符, 名字或者值,
+// 接口字段为子节点, 切片字段为参数
+type checker struct {
+	pkg   string
+	funcs map[string]reflect.Type
+}
+
+func nodeValue(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func fieldsOf(v reflect.Value, kind reflect.Kind) []reflect.Value {
+	var _fs []reflect.Value
+	for i := 0; i < v.NumField(); i++ {
+		if v.Field(i).Kind() == kind {
+			_fs = append(_fs, v.Field(i))
+		}
+	}
+	return _fs
+}
+
+func textOf(v reflect.Value) string {
+	if _fs := fieldsOf(v, reflect.String); len(_fs) > 0 {
+		return _fs[0].String()
+	}
+	return ""
+}
+
+// kind 节点结果的类型, 不能确定时为空
+func (t *checker) kind(v reflect.Value) string {
+	switch v.Type().Name() {
+	case "boolNode", "matchesNode":
+		return "bool"
+	case "numberNode":
+		return "number"
+	case "textNode":
+		return "string"
+	case "nilNode":
+		return "nil"
+	case "arrayNode":
+		return "array"
+	case "mapNode":
+		return "map"
+	case "builtinNode":
+		return "number"
+	case "unaryNode":
+		if _op := textOf(v); _op == "not" || _op == "!" {
+			return "bool"
+		}
+		return "number"
+	case "binaryNode":
+		switch _op := textOf(v); {
+		case kBoolOps[_op]:
+			return "bool"
+		case _op == "~":
+			return "string"
+		case _op == "..":
+			return "array"
+		}
+		return "number"
+	case "conditionalNode":
+		_ns := fieldsOf(v, reflect.Interface)
+		if len(_ns) != 3 {
+			return ""
+		}
+		_a, _b := t.kind(nodeValue(_ns[1])), t.kind(nodeValue(_ns[2]))
+		if _a == "bool" && _b == "bool" {
+			return "bool"
+		}
+		if _a != "" && _a != "bool" {
+			return _a
+		}
+		if _b != "" && _b != "bool" {
+			return _b
+		}
+	case "functionNode":
+		_fn, ok := t.funcs[textOf(v)]
+		if ok && _fn.NumOut() > 0 && _fn.Out(0).Kind() == reflect.Bool {
+			return "bool"
+		}
+	}
+	return ""
+}
+
+// literals 检查全部函数调用中字符串字面量参数的值
+func (t *checker) literals(v reflect.Value) error {
+	if v.Type().Name() == "functionNode" {
+		_name := textOf(v)
+		if _fc := kParamChecks[_name]; _fc != nil {
+			for _, _args := range fieldsOf(v, reflect.Slice) {
+				for i := 0; i < _args.Len(); i++ {
+					_a := nodeValue(_args.Index(i))
+					if !_a.IsValid() || _a.Type().Name() != "textNode" {
+						continue
+					}
+					if err := _fc(textOf(_a)); err != nil {
+						return fmt.Errorf("函数[%s]的第%d个参数[%s]错误: %s", _name, i+1, textOf(_a), err)
+					}
+				}
+			}
+		}
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		if err := t.children(v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// children 只进入expr包中的节点
+func (t *checker) children(f reflect.Value) error {
+	if f.Kind() == reflect.Slice {
+		for i := 0; i < f.Len(); i++ {
+			if err := t.children(f.Index(i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	_n := nodeValue(f)
+	if !_n.IsValid() || _n.Kind() != reflect.Struct || _n.Type().PkgPath() != t.pkg {
+		return nil
+	}
+	return t.literals(_n)
+}
@@ -0,0 +1,164 @@
+package validator
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// 日期和时间的校验函数, 字段的值可以是时间字符串, unix秒或者to_time转换之后的time.Time
+// 字符串没有时区时按照TZ设置的时区解析, 默认UTC
+//
+//	birthday = ['IsDate("2006-01-02") && Before("today")', "生日格式错误"]
+//	start_at = ['TZ("Asia/Shanghai") && After("now") && Within("720h")', "开始时间必须在30天之内"]
+//	end_at = ['After(Field("start_at"))', "结束时间必须在开始时间之后"]
+
+// kDateLayouts 没有指定layout时依次尝试的格式
+var kDateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// TZ 设置同一条规则中后面的日期函数使用的时区, 例如 Asia/Shanghai, 不影响带时区的字符串
+func (t *KValidator) TZ(name string) bool {
+	_loc, err := time.LoadLocation(name)
+	if err != nil {
+		return t.fail("时区[%s]不存在: %s", name, err)
+	}
+	t.loc = _loc
+	return true
+}
+
+func (t *KValidator) location() *time.Location {
+	if t.loc == nil {
+		return time.UTC
+	}
+	return t.loc
+}
+
+// parseTime 把时间字符串, unix秒或者time.Time转换成时间, 没有layout时依次尝试kDateLayouts
+func (t *KValidator) parseTime(v reflect.Value, layouts ...string) (time.Time, bool) {
+	if !v.IsValid() {
+		return time.Time{}, false
+	}
+
+	if v.Type() == timeType {
+		return v.Interface().(time.Time), true
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		if len(layouts) == 0 {
+			layouts = kDateLayouts
+		}
+
+		for _, _l := range layouts {
+			if _t, err := time.ParseInLocation(_l, strings.TrimSpace(v.String()), t.location()); err == nil {
+				return _t, true
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Float32, reflect.Float64:
+		if len(layouts) == 0 {
+			_n, _ := number(v)
+			return time.Unix(int64(_n), 0).In(t.location()), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// bound 比较的时间, 可以是时间字符串, time.Time, now, today(TZ时区的当天零点),
+// 或者相对现在的时间间隔, 例如 -720h, +24h
+func (t *KValidator) bound(param interface{}) (time.Time, bool) {
+	_now := time.Now().In(t.location())
+	if _s, ok := param.(string); ok {
+		switch _s = strings.TrimSpace(_s); {
+		case _s == "now":
+			return _now, true
+		case _s == "today":
+			return time.Date(_now.Year(), _now.Month(), _now.Day(), 0, 0, 0, 0, _now.Location()), true
+		case strings.HasPrefix(_s, "+") || strings.HasPrefix(_s, "-"):
+			if _d, err := time.ParseDuration(_s); err == nil {
+				return _now.Add(_d), true
+			}
+		}
+	}
+
+	if _t, ok := t.parseTime(reflect.Indirect(reflect.ValueOf(param))); ok {
+		return _t, true
+	}
+	return time.Time{}, t.fail("参数[%v]不是时间", param)
+}
+
+// date 字段的时间, 不能解析时校验失败
+func (t *KValidator) date() (time.Time, bool) {
+	if _t, ok := t.parseTime(t.value()); ok {
+		return _t, true
+	}
+	return time.Time{}, t.fail("值[%v]不是时间", t.data)
+}
+
+// IsDate 字符串是否符合layout, 默认依次尝试RFC3339, 2006-01-02T15:04:05, 2006-01-02 15:04:05和2006-01-02
+func (t *KValidator) IsDate(layout ...string) bool {
+	_f := t.value()
+	if _f.Kind() != reflect.String {
+		return t.fail("字段类型[%s]不是字符串", _f.Kind())
+	}
+
+	if _, ok := t.parseTime(_f, layout...); !ok {
+		return t.fail("[%s]不符合日期格式%v", _f.String(), layout)
+	}
+	return true
+}
+
+// Before 在param之前
+func (t *KValidator) Before(param interface{}) bool {
+	_t, ok := t.date()
+	if !ok {
+		return false
+	}
+
+	_b, ok := t.bound(param)
+	return ok && _t.Before(_b)
+}
+
+// After 在param之后
+func (t *KValidator) After(param interface{}) bool {
+	_t, ok := t.date()
+	if !ok {
+		return false
+	}
+
+	_b, ok := t.bound(param)
+	return ok && _t.After(_b)
+}
+
+// Within 和现在相差不超过d, 例如 720h, 带符号时只允许一个方向, +720h为未来30天之内, -720h为过去30天之内
+func (t *KValidator) Within(d string) bool {
+	_t, ok := t.date()
+	if !ok {
+		return false
+	}
+
+	_d, err := time.ParseDuration(d)
+	if err != nil {
+		return t.fail("时间间隔[%s]格式错误: %s", d, err)
+	}
+
+	_diff := _t.Sub(time.Now())
+	switch {
+	case strings.HasPrefix(d, "+"):
+		return _diff >= 0 && _diff <= _d
+	case strings.HasPrefix(d, "-"):
+		return _diff <= 0 && _diff >= _d
+	}
+	return _diff <= _d && _diff >= -_d
+}
+
+// Field 文档中其他字段的值, 用于和其他字段比较, 路径和表单字段的写法一样, 例如 Field("items[0].qty")
+// 字段不存在或者路径中有通配符时为nil
+func (t *KValidator) Field(path string) interface{} {
+	_p, err := ParsePath(path)
+	if err != nil {
+		return nil
+	}
+
+	_v, _ := _p.Get(t.doc)
+	return _v
+}
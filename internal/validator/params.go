@@ -0,0 +1,269 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 带参数的校验函数, 例如 Gte(18), Len(3, 32), Contains("@"), OneOf("a", "b"), Match("^[a-z]+$")
+// 字段的值来自json解析, 数字为float64, 数组为[]interface{}, 对象为map[string]interface{}
+// 字段和数字比较时: 字符串比较字符数, 数组和对象比较长度, 数字比较大小
+
+var regexCache sync.Map
+
+// value 去掉interface和指针之后的字段值
+func (t *KValidator) value() reflect.Value {
+	_v := t.data
+	for _v.Kind() == reflect.Interface || _v.Kind() == reflect.Ptr {
+		if _v.IsNil() {
+			return reflect.Value{}
+		}
+		_v = _v.Elem()
+	}
+	return _v
+}
+
+func (t *KValidator) fail(format string, args ...interface{}) bool {
+	t.err = fmt.Sprintf(format, args...)
+	return false
+}
+
+// number 把数字或者数字字符串转换成float64
+func number(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v.String()), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// length 字符串的字符数, 数组和对象的长度
+func length(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	}
+	return 0, false
+}
+
+// size 字符串和数组的长度, 或者数字本身
+func size(v reflect.Value) (float64, bool) {
+	if _l, ok := length(v); ok {
+		return _l, true
+	}
+	return number(v)
+}
+
+func sign(f float64) int {
+	switch {
+	case f < 0:
+		return -1
+	case f > 0:
+		return 1
+	}
+	return 0
+}
+
+// compare 比较字段和参数, 返回-1, 0, 1
+func (t *KValidator) compare(param interface{}) (int, bool) {
+	_f := t.value()
+	_p := reflect.Indirect(reflect.ValueOf(param))
+
+	if _f.IsValid() && _f.Type() == timeType {
+		_pt, ok := param.(time.Time)
+		if !ok {
+			return 0, t.fail("参数[%v]不是时间", param)
+		}
+
+		_ft := _f.Interface().(time.Time)
+		return sign(float64(_ft.Sub(_pt))), true
+	}
+
+	if _f.Kind() == reflect.String && _p.Kind() == reflect.String {
+		return strings.Compare(_f.String(), _p.String()), true
+	}
+
+	_n, ok := number(_p)
+	if !ok {
+		return 0, t.fail("参数[%v]不是数字", param)
+	}
+
+	_s, ok := size(_f)
+	if !ok {
+		return 0, t.fail("字段类型[%s]不能比较大小", _f.Kind())
+	}
+	return sign(_s - _n), true
+}
+
+// Gte 大于等于, 字符串和数组比较长度
+func (t *KValidator) Gte(param interface{}) bool {
+	_c, ok := t.compare(param)
+	return ok && _c >= 0
+}
+
+// Gt 大于, 字符串和数组比较长度
+func (t *KValidator) Gt(param interface{}) bool {
+	_c, ok := t.compare(param)
+	return ok && _c > 0
+}
+
+// Lte 小于等于, 字符串和数组比较长度
+func (t *KValidator) Lte(param interface{}) bool {
+	_c, ok := t.compare(param)
+	return ok && _c <= 0
+}
+
+// Lt 小于, 字符串和数组比较长度
+func (t *KValidator) Lt(param interface{}) bool {
+	_c, ok := t.compare(param)
+	return ok && _c < 0
+}
+
+// Min 同Gte
+func (t *KValidator) Min(param interface{}) bool {
+	return t.Gte(param)
+}
+
+// Max 同Lte
+func (t *KValidator) Max(param interface{}) bool {
+	return t.Lte(param)
+}
+
+// Eq 等于, 布尔值直接比较, 字符串和字符串比较内容, 和数字比较时比较长度或者大小
+func (t *KValidator) Eq(param interface{}) bool {
+	if _b, ok := param.(bool); ok {
+		_f := t.value()
+		return _f.Kind() == reflect.Bool && _f.Bool() == _b
+	}
+
+	_c, ok := t.compare(param)
+	return ok && _c == 0
+}
+
+// Ne 不等于, 不能比较时校验不通过
+func (t *KValidator) Ne(param interface{}) bool {
+	if _b, ok := param.(bool); ok {
+		_f := t.value()
+		return _f.Kind() == reflect.Bool && _f.Bool() != _b
+	}
+
+	_c, ok := t.compare(param)
+	return ok && _c != 0
+}
+
+// Len 长度等于min, 或者在[min, max]之间, 只支持字符串, 数组和对象
+func (t *KValidator) Len(min interface{}, max ...interface{}) bool {
+	_l, ok := length(t.value())
+	if !ok {
+		return t.fail("字段类型[%s]没有长度", t.value().Kind())
+	}
+
+	_min, ok := number(reflect.ValueOf(min))
+	if !ok {
+		return t.fail("参数[%v]不是数字", min)
+	}
+
+	if len(max) == 0 {
+		return _l == _min
+	}
+
+	_max, ok := number(reflect.ValueOf(max[0]))
+	if !ok {
+		return t.fail("参数[%v]不是数字", max[0])
+	}
+	return _l >= _min && _l <= _max
+}
+
+// equal 字段和参数是否相等, 数字按照大小比较, 其他类型必须一致
+func equal(v reflect.Value, param interface{}) bool {
+	_p := reflect.ValueOf(param)
+	switch v.Kind() {
+	case reflect.String:
+		return _p.Kind() == reflect.String && v.String() == _p.String()
+	case reflect.Bool:
+		return _p.Kind() == reflect.Bool && v.Bool() == _p.Bool()
+	case reflect.Invalid:
+		return param == nil
+	}
+
+	if _p.Kind() == reflect.String {
+		return false
+	}
+
+	_f, ok := number(v)
+	_n, _ok := number(_p)
+	return ok && _ok && _f == _n
+}
+
+// OneOf 字段的值是参数中的一个
+func (t *KValidator) OneOf(params ...interface{}) bool {
+	_f := t.value()
+	for _, _p := range params {
+		if equal(_f, _p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains 字符串包含子串, 数组包含元素, 对象包含键
+func (t *KValidator) Contains(param interface{}) bool {
+	_f := t.value()
+	switch _f.Kind() {
+	case reflect.String:
+		_s, ok := param.(string)
+		return ok && strings.Contains(_f.String(), _s)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < _f.Len(); i++ {
+			if equal(reflect.Indirect(reflect.ValueOf(_f.Index(i).Interface())), param) {
+				return true
+			}
+		}
+		return false
+	case reflect.Map:
+		_k := reflect.ValueOf(param)
+		if !_k.IsValid() || !_k.Type().AssignableTo(_f.Type().Key()) {
+			return false
+		}
+		return _f.MapIndex(_k).IsValid()
+	}
+	return t.fail("字段类型[%s]不支持Contains", _f.Kind())
+}
+
+// Match 字段(字符串或者数字)匹配正则表达式
+func (t *KValidator) Match(pattern string) bool {
+	_re, ok := regexCache.Load(pattern)
+	if !ok {
+		_r, err := regexp.Compile(pattern)
+		if err != nil {
+			return t.fail("正则表达式[%s]错误: %s", pattern, err)
+		}
+		_re, _ = regexCache.LoadOrStore(pattern, _r)
+	}
+
+	_f := t.value()
+	switch _f.Kind() {
+	case reflect.String:
+		return _re.(*regexp.Regexp).MatchString(_f.String())
+	case reflect.Float32, reflect.Float64:
+		return _re.(*regexp.Regexp).MatchString(strconv.FormatFloat(_f.Float(), 'f', -1, 64))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return _re.(*regexp.Regexp).MatchString(strconv.FormatInt(_f.Int(), 10))
+	}
+	return t.fail("字段类型[%s]不支持Match", _f.Kind())
+}
@@ -0,0 +1,79 @@
+package validator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PathSeg 字段路径中的一段, 键, 数组下标或者通配符
+type PathSeg struct {
+	Key      string
+	Index    int
+	Wildcard bool
+}
+
+// Path 字段路径, 例如 address.city, items[*].qty, tags[], items[0].name
+type Path []PathSeg
+
+func ParsePath(s string) (Path, error) {
+	var _p Path
+	for _, _part := range strings.Split(s, ".") {
+		_key := _part
+		_rest := ""
+		if i := strings.Index(_part, "["); i >= 0 {
+			_key, _rest = _part[:i], _part[i:]
+		}
+
+		if _key == "" {
+			return nil, fmt.Errorf("路径[%s]格式错误, 键不能为空", s)
+		}
+		_p = append(_p, PathSeg{Key: _key, Index: -1})
+
+		for _rest != "" {
+			i := strings.Index(_rest, "]")
+			if _rest[0] != '[' || i < 0 {
+				return nil, fmt.Errorf("路径[%s]格式错误, []不匹配", s)
+			}
+
+			switch _idx := _rest[1:i]; _idx {
+			case "", "*":
+				_p = append(_p, PathSeg{Index: -1, Wildcard: true})
+			default:
+				n, err := strconv.Atoi(_idx)
+				if err != nil || n < 0 {
+					return nil, fmt.Errorf("路径[%s]格式错误, 下标[%s]不是非负整数", s, _idx)
+				}
+				_p = append(_p, PathSeg{Index: n})
+			}
+			_rest = _rest[i+1:]
+		}
+	}
+	return _p, nil
+}
+
+// Get 按照路径取单个值, 路径中有通配符或者值不存在时返回false
+func (t Path) Get(doc interface{}) (interface{}, bool) {
+	_v := doc
+	for _, _s := range t {
+		switch _d := _v.(type) {
+		case map[string]interface{}:
+			if _s.Key == "" {
+				return nil, false
+			}
+			_n, ok := _d[_s.Key]
+			if !ok {
+				return nil, false
+			}
+			_v = _n
+		case []interface{}:
+			if _s.Key != "" || _s.Wildcard || _s.Index >= len(_d) {
+				return nil, false
+			}
+			_v = _d[_s.Index]
+		default:
+			return nil, false
+		}
+	}
+	return _v, true
+}
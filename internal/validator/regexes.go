@@ -46,6 +46,12 @@ const (
 	uRLEncodedRegexString            = `(%[A-Fa-f0-9]{2})`
 	hTMLEncodedRegexString           = `&#[x]?([0-9a-fA-F]{2})|(&gt)|(&lt)|(&quot)|(&amp)+[;]?`
 	hTMLRegexString                  = `<[/]?([a-zA-Z]+).*?>`
+	chinaMobileRegexString           = `^(?:\+?86[- ]?)?1[3-9]\d{9}$`
+	chinaIDCardRegexString           = `^[1-9]\d{5}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]$`
+	uSCCRegexString                  = `^[0-9A-HJ-NPQRTUWXY]{2}\d{6}[0-9A-HJ-NPQRTUWXY]{10}$` // unified social credit code GB 32100-2015
+	bankCardRegexString              = `^\d{12,19}$`
+	chinaPostcodeRegexString         = `^[0-8]\d{5}$`
+	chinaPlateRegexString            = `^[京津沪渝冀豫云辽黑湘皖鲁新苏浙赣鄂桂甘晋蒙陕吉闽贵粤青藏川宁琼][A-HJ-NP-Z](?:[A-HJ-NP-Z0-9]{4}[A-HJ-NP-Z0-9挂学警港澳]|[DF][A-HJ-NP-Z0-9]\d{4}|\d{5}[DF])$`
 )
 
 var (
@@ -92,4 +98,43 @@ var (
 	uRLEncodedRegex            = regexp.MustCompile(uRLEncodedRegexString)
 	hTMLEncodedRegex           = regexp.MustCompile(hTMLEncodedRegexString)
 	hTMLRegex                  = regexp.MustCompile(hTMLRegexString)
+	chinaMobileRegex           = regexp.MustCompile(chinaMobileRegexString)
+	chinaIDCardRegex           = regexp.MustCompile(chinaIDCardRegexString)
+	uSCCRegex                  = regexp.MustCompile(uSCCRegexString)
+	bankCardRegex              = regexp.MustCompile(bankCardRegexString)
+	chinaPostcodeRegex         = regexp.MustCompile(chinaPostcodeRegexString)
+	chinaPlateRegex            = regexp.MustCompile(chinaPlateRegexString)
 )
+
+// Patterns 只用正则表达式判断字符串的校验函数和对应的正则表达式, 用于生成客户端的校验代码
+// IsNumber和IsNumeric对数字类型直接通过
+var Patterns = map[string]string{
+	"IsAlpha":           alphaRegexString,
+	"IsAlphanum":        alphaNumericRegexString,
+	"IsAlphanumUnicode": alphaUnicodeNumericRegexString,
+	"IsNumber":          numberRegexString,
+	"IsNumeric":         numericRegexString,
+	"IsHexadecimal":     hexadecimalRegexString,
+	"IsHEXColor":        hexcolorRegexString,
+	"IsRGB":             rgbRegexString,
+	"IsRGBA":            rgbaRegexString,
+	"IsHSL":             hslRegexString,
+	"IsHSLA":            hslaRegexString,
+	"IsEmail":           emailRegexString,
+	"IsBase64":          base64RegexString,
+	"IsBase64URL":       base64URLRegexString,
+	"IsUUID":            uUIDRegexString,
+	"IsUUID3":           uUID3RegexString,
+	"IsUUID4":           uUID4RegexString,
+	"IsUUID5":           uUID5RegexString,
+	"IsUUIDRFC4122":     uUIDRFC4122RegexString,
+	"IsUUID3RFC4122":    uUID3RFC4122RegexString,
+	"IsUUID4RFC4122":    uUID4RFC4122RegexString,
+	"IsUUID5RFC4122":    uUID5RFC4122RegexString,
+	"IsASCII":           aSCIIRegexString,
+	"IsPrintableASCII":  printableASCIIRegexString,
+	"IsHostnameRFC1123": hostnameRegexStringRFC1123,
+	"IsChinaMobile":     chinaMobileRegexString,
+	"IsChinaPostcode":   chinaPostcodeRegexString,
+	"IsChinaPlate":      chinaPlateRegexString,
+}
@@ -0,0 +1,181 @@
+package validator
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// 注册的校验函数, 在字段规则中和KValidator的方法一样调用
+// 函数的参数依次为: 可选的context.Context, 字段的值, 规则中的参数, 例如
+//
+//	func(v string) bool                                            IsOrderNo()
+//	func(ctx context.Context, v string, n float64) (bool, string)  Quota(3)
+//
+// 返回值可以是 bool, (bool, string) 或者 error, string和error作为校验失败的原因
+var registry = struct {
+	sync.RWMutex
+	funcs   map[string]reflect.Value
+	version int
+}{funcs: make(map[string]reflect.Value)}
+
+var (
+	ctxType    = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errType    = reflect.TypeOf((*error)(nil)).Elem()
+	boolType   = reflect.TypeOf(true)
+	stringType = reflect.TypeOf("")
+)
+
+// Register 注册校验函数, 需要在加载表单规则之前注册, 加载时检查规则中函数的参数和类型
+// 不能和KValidator的方法重名, 重复注册时后者覆盖前者
+func Register(name string, fn interface{}) error {
+	if !isIdent(name) {
+		return fmt.Errorf("校验函数名[%s]格式错误", name)
+	}
+
+	if _, ok := reflect.TypeOf(&KValidator{}).MethodByName(name); ok {
+		return fmt.Errorf("校验函数[%s]和内置的校验函数重名", name)
+	}
+
+	_fn := reflect.ValueOf(fn)
+	if _fn.Kind() != reflect.Func || _fn.IsNil() {
+		return fmt.Errorf("校验函数[%s]的类型是%T, 应该是函数", name, fn)
+	}
+
+	_t := _fn.Type()
+	_skip := 0
+	if _t.NumIn() > 0 && _t.In(0) == ctxType {
+		_skip = 1
+	}
+	if _t.NumIn() <= _skip || _t.IsVariadic() && _t.NumIn() == _skip+1 {
+		return fmt.Errorf("校验函数[%s]缺少字段值参数", name)
+	}
+
+	switch {
+	case _t.NumOut() == 1 && (_t.Out(0) == boolType || _t.Out(0) == errType):
+	case _t.NumOut() == 2 && _t.Out(0) == boolType && _t.Out(1) == stringType:
+	default:
+		return fmt.Errorf("校验函数[%s]的返回值应该是bool, (bool, string)或者error", name)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	registry.funcs[name] = _fn
+	registry.version++
+	return nil
+}
+
+// Env 字段规则的表达式环境, 包含t的方法和注册的函数
+func Env(t *KValidator) map[string]interface{} {
+	_v := reflect.ValueOf(t)
+	_t := _v.Type()
+
+	registry.RLock()
+	defer registry.RUnlock()
+
+	_env := make(map[string]interface{}, _t.NumMethod()+len(registry.funcs))
+	for i := 0; i < _t.NumMethod(); i++ {
+		_env[_t.Method(i).Name] = _v.Method(i).Interface()
+	}
+	for _name, _fn := range registry.funcs {
+		_env[_name] = t.bind(_name, _fn)
+	}
+	return _env
+}
+
+// environment t的表达式环境, 只在第一次使用或者注册了新的函数之后构建
+func (t *KValidator) environment() map[string]interface{} {
+	registry.RLock()
+	_version := registry.version
+	registry.RUnlock()
+
+	if t.env == nil || t.version != _version {
+		t.env, t.version = Env(t), _version
+	}
+	return t.env
+}
+
+// bind 把注册的函数转换成只有规则参数并且返回bool的函数, 字段的值和context来自t
+func (t *KValidator) bind(name string, fn reflect.Value) interface{} {
+	_t := fn.Type()
+	_skip := 1
+	if _t.In(0) == ctxType {
+		_skip = 2
+	}
+
+	_in := make([]reflect.Type, 0, _t.NumIn()-_skip)
+	for i := _skip; i < _t.NumIn(); i++ {
+		_in = append(_in, _t.In(i))
+	}
+
+	_ft := reflect.FuncOf(_in, []reflect.Type{boolType}, _t.IsVariadic())
+	return reflect.MakeFunc(_ft, func(args []reflect.Value) []reflect.Value {
+		_v, ok := convert(t.value(), _t.In(_skip-1))
+		if !ok {
+			return []reflect.Value{reflect.ValueOf(t.fail("%s不支持类型%s", name, t.value().Kind()))}
+		}
+
+		_args := append([]reflect.Value{_v}, args...)
+		if _skip == 2 {
+			_args = append([]reflect.Value{reflect.ValueOf(t.context())}, _args...)
+		}
+
+		var _out []reflect.Value
+		if _t.IsVariadic() {
+			_out = fn.CallSlice(_args)
+		} else {
+			_out = fn.Call(_args)
+		}
+
+		_ok := true
+		switch {
+		case len(_out) == 2:
+			_ok = _out[0].Bool()
+			if _msg := _out[1].String(); !_ok && _msg != "" {
+				t.err = _msg
+			}
+		case _out[0].Type() == boolType:
+			_ok = _out[0].Bool()
+		case !_out[0].IsNil():
+			_ok = t.fail("%s", _out[0].Interface().(error))
+		}
+		return []reflect.Value{reflect.ValueOf(_ok)}
+	}).Interface()
+}
+
+func (t *KValidator) context() context.Context {
+	if t.ctx == nil {
+		return context.Background()
+	}
+	return t.ctx
+}
+
+// convert 把字段的值转换成参数的类型, null转换成零值, 数字之间可以互相转换, 但是不能丢失精度
+func convert(v reflect.Value, typ reflect.Type) (reflect.Value, bool) {
+	switch {
+	case !v.IsValid():
+		return reflect.Zero(typ), true
+	case v.Type().AssignableTo(typ):
+		_v := reflect.New(typ).Elem()
+		_v.Set(v)
+		return _v, true
+	case isNumber(v.Kind()) && isNumber(typ.Kind()):
+		_v := v.Convert(typ)
+		return _v, _v.Convert(v.Type()).Interface() == v.Interface()
+	}
+	return v, false
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+func isIdent(s string) bool {
+	for i, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"math"
 	"reflect"
 )
 
@@ -43,3 +44,48 @@ func (t *KValidator) IsAlphanumUnicode() bool {
 func (t *KValidator) isAlphaUnicode() bool {
 	return alphaUnicodeRegex.MatchString(t.data.String())
 }
+
+// kJSONTypes JSON Schema的类型
+var kJSONTypes = map[string]bool{"string": true, "number": true, "integer": true, "boolean": true, "array": true, "object": true, "null": true}
+
+// IsType is the validation function for validating if the current field's value is one of the JSON Schema types:
+// string, number, integer, boolean, array, object or null.
+func (t *KValidator) IsType(types ...string) bool {
+	_v := t.value()
+	_k := _v.Kind()
+	for _, _t := range types {
+		switch _t {
+		case "null":
+			if !_v.IsValid() {
+				return true
+			}
+		case "string":
+			if _k == reflect.String {
+				return true
+			}
+		case "number":
+			if isNumber(_k) {
+				return true
+			}
+		case "integer":
+			if _n, ok := number(_v); ok && isNumber(_k) && _n == math.Trunc(_n) {
+				return true
+			}
+		case "boolean":
+			if _k == reflect.Bool {
+				return true
+			}
+		case "array":
+			if _k == reflect.Slice || _k == reflect.Array {
+				return true
+			}
+		case "object":
+			if _k == reflect.Map {
+				return true
+			}
+		default:
+			return t.fail("类型[%s]不是JSON Schema的类型", _t)
+		}
+	}
+	return t.fail("字段类型[%s]不是%v", _k, types)
+}
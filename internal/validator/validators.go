@@ -1,34 +1,70 @@
 package validator
 
 import (
+	"context"
+	"fmt"
 	"github.com/antonmedv/expr"
-	"github.com/kooksee/kweb/internal/g"
 	"reflect"
+	"sync"
+	"time"
 )
 
 func KValidatorOf(d interface{}) *KValidator {
 	return &KValidator{data: reflect.ValueOf(d)}
 }
 
+// pool 复用KValidator和绑定到它的表达式环境, 每次校验只设置字段的值, context和文档
+var pool = sync.Pool{New: func() interface{} { return &KValidator{} }}
+
+// Validate 执行字段规则, ctx传给注册的校验函数, doc为整个文档, Field从中取其他字段的值
+func Validate(ctx context.Context, doc map[string]interface{}, d interface{}, node expr.Node) (bool, string) {
+	t := pool.Get().(*KValidator)
+	defer func() {
+		*t = KValidator{env: t.env, version: t.version}
+		pool.Put(t)
+	}()
+
+	t.data, t.ctx, t.doc = reflect.ValueOf(d), ctx, doc
+	return t.Do(node)
+}
+
 type KValidator struct {
 	data reflect.Value
 	err  string
+	ctx  context.Context
+	doc  map[string]interface{}
+	loc  *time.Location
+
+	// env 绑定到t的表达式环境, version为构建时注册表的版本
+	env     map[string]interface{}
+	version int
 }
 
-func (t *KValidator) do(node expr.Node, dt interface{}) (bool, string) {
+// do 执行规则, 规则执行失败或者结果不是bool时校验不通过, 原因作为错误信息返回
+func (t *KValidator) do(node expr.Node, dt interface{}) (ok bool, msg string) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok, msg = false, fmt.Sprintf("校验规则执行失败: %v", r)
+		}
+	}()
+
 	out, err := expr.Run(node, dt)
-	g.AssertErr(err, "校验规则执行失败")
+	if err != nil {
+		return false, fmt.Sprintf("校验规则执行失败: %s", err)
+	}
 
 	ok, isBool := out.(bool)
-	g.AssertBool(!isBool, "校验规则结果类型错误")
+	if !isBool {
+		return false, fmt.Sprintf("校验规则结果类型错误: %T", out)
+	}
 
 	return ok, t.err
 }
 
 func (t *KValidator) Do(node expr.Node) (bool, string) {
-	return t.do(node, t)
+	return t.do(node, t.environment())
 }
 
 func (t *KValidator) Eval(node expr.Node) (bool, string) {
-	return t.do(node, t.data)
+	return t.do(node, t.data.Interface())
 }
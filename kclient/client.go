@@ -0,0 +1,171 @@
+package kclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/kooksee/kweb/internal/g"
+	"github.com/kooksee/kweb/kerrors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Client 调用其他kweb服务的http客户端
+// 错误响应被解码成*kerrors.KError, 可以用errors.Is和同一份错误目录中的错误比较
+// 错误目录中标记为retryable的错误会按照指数退避重试
+type Client struct {
+	HTTP       *http.Client
+	Errors     *kerrors.KErrors
+	MaxRetries int
+	Backoff    time.Duration
+}
+
+func New(errs *kerrors.KErrors) *Client {
+	return &Client{
+		HTTP:       http.DefaultClient,
+		Errors:     errs,
+		MaxRetries: 2,
+		Backoff:    100 * time.Millisecond,
+	}
+}
+
+// Do 发送请求, 状态码>=400时返回*kerrors.KError
+func (t *Client) Do(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.GetBody == nil {
+		_body, err := ioutil.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(_body)), nil
+		}
+		req.Body, _ = req.GetBody()
+	}
+
+	for i := 0; ; i++ {
+		resp, err := t.HTTP.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode < http.StatusBadRequest {
+			return resp, nil
+		}
+
+		_e := t.Decode(resp)
+		if !_e.Retryable || i >= t.MaxRetries {
+			return nil, _e
+		}
+
+		if err := sleep(req.Context(), t.Backoff<<uint(i)); err != nil {
+			return nil, &CanceledError{Err: err, Last: _e}
+		}
+
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// DoJSON 以json发送in, 成功时把响应解码到out, in和out可以为nil
+func (t *Client) DoJSON(ctx context.Context, method, url string, in, out interface{}) error {
+	var _body io.Reader
+	if in != nil {
+		_dt, err := g.Json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		_body = bytes.NewReader(_dt)
+	}
+
+	req, err := http.NewRequest(method, url, _body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := t.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return g.Json.NewDecoder(resp.Body).Decode(out)
+}
+
+// Decode 把错误响应解码成*kerrors.KError, 响应不是kweb的错误格式时返回未知错误
+func (t *Client) Decode(resp *http.Response) *kerrors.KError {
+	defer resp.Body.Close()
+
+	_dt, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return t.unknown(resp).WithCause(err)
+	}
+
+	var _env kerrors.ErrorEnvelope
+	if err := g.Json.Unmarshal(_dt, &_env); err != nil || _env.Code == "" {
+		return t.unknown(resp).WithCause(fmt.Errorf("响应不是kweb错误: %s", _dt))
+	}
+
+	if t.Errors == nil {
+		return (&kerrors.KErrors{}).FromEnvelope(resp.StatusCode, &_env)
+	}
+	return t.Errors.FromEnvelope(resp.StatusCode, &_env)
+}
+
+func (t *Client) unknown(resp *http.Response) *kerrors.KError {
+	return (&kerrors.KErrors{}).FromEnvelope(resp.StatusCode, &kerrors.ErrorEnvelope{Code: kerrors.UnknownCode, Msg: resp.Status})
+}
+
+// CanceledError 等待重试时context结束, Err是context的错误, Last是最后一次响应的错误
+// errors.Is可以和context.Canceled或者Last比较, errors.As可以取出Last
+type CanceledError struct {
+	Err  error
+	Last *kerrors.KError
+}
+
+func (t *CanceledError) Error() string {
+	return fmt.Sprintf("%s, 最后一次响应: %s", t.Err, t.Last)
+}
+
+func (t *CanceledError) Unwrap() error {
+	return t.Err
+}
+
+func (t *CanceledError) Is(target error) bool {
+	return errors.Is(t.Last, target)
+}
+
+func (t *CanceledError) As(target interface{}) bool {
+	_p, ok := target.(**kerrors.KError)
+	if ok {
+		*_p = t.Last
+	}
+	return ok
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	_t := time.NewTimer(d)
+	defer _t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-_t.C:
+		return nil
+	}
+}
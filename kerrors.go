@@ -1,28 +1,24 @@
 package kweb
 
 import (
-	"fmt"
-	"github.com/BurntSushi/toml"
-	"github.com/kooksee/kweb/internal/g"
+	"github.com/kooksee/kweb/kerrors"
 )
 
-type KError struct {
-	Code string
-	Msg  string
-}
+// 错误目录在kerrors包中实现, 不依赖gin和数据库驱动, kclient也使用这个包
+// 这里保留原来的名字
 
-type KErrors struct {
-	data map[string]map[string][]string
-}
+type (
+	KError         = kerrors.KError
+	KErrors        = kerrors.KErrors
+	KErrorDef      = kerrors.KErrorDef
+	KErrorsProblem = kerrors.KErrorsProblem
+	ErrorEnvelope  = kerrors.ErrorEnvelope
+)
 
-func (t *KErrors) FromPath(cfg string) {
-	_, err := toml.DecodeFile(cfg, &t.data)
-	g.AssertErr(err, "文件[%s]解析失败", cfg)
-}
+// UnknownCode 获取没有定义的错误时返回的code
+const UnknownCode = kerrors.UnknownCode
 
-func (t *KErrors) Get(ns, name string, args ...interface{}) *KError {
-	return &KError{
-		Code: t.data[ns][name][0],
-		Msg:  fmt.Sprintf(t.data[ns][name][1], args...),
-	}
-}
+var (
+	GenErrors    = kerrors.GenErrors
+	ExportErrors = kerrors.ExportErrors
+)
@@ -0,0 +1,709 @@
+package kerrors
+
+import (
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/kooksee/kweb/internal/g"
+	"github.com/kooksee/kweb/internal/kmsg"
+	"github.com/kooksee/kweb/internal/ktoml"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const kErrorStackDepth = 32
+
+// UnknownCode 获取没有定义的错误时返回的code
+const UnknownCode = "-1"
+
+const (
+	unknownNs   = "kweb"
+	unknownName = "unknown"
+)
+
+// KError 错误定义的一个实例, 实现了error接口
+// 可以携带引起该错误的原因(cause)以及创建时的调用栈
+type KError struct {
+	Ns        string
+	Name      string
+	Code      string
+	Msg       string
+	Status    int
+	Level     string
+	Retryable bool
+
+	exit  int
+	cause error
+	stack []uintptr
+}
+
+func newKError(ns, name, code, msg string) *KError {
+	_pcs := make([]uintptr, kErrorStackDepth)
+	_n := runtime.Callers(3, _pcs)
+	return &KError{Ns: ns, Name: name, Code: code, Msg: msg, stack: _pcs[:_n]}
+}
+
+func (t *KError) Error() string {
+	if t.cause == nil {
+		return t.Code + ": " + t.Msg
+	}
+	return t.Code + ": " + t.Msg + ": " + t.cause.Error()
+}
+
+// WithCause 设置引起该错误的原因
+func (t *KError) WithCause(err error) *KError {
+	t.cause = err
+	return t
+}
+
+func (t *KError) Unwrap() error {
+	return t.cause
+}
+
+// Is 支持errors.Is, 优先按照namespace+name匹配, 没有名字的按照code匹配
+func (t *KError) Is(target error) bool {
+	_t, ok := target.(*KError)
+	if !ok {
+		return false
+	}
+
+	if _t.Ns != "" && _t.Name != "" && t.Ns != "" && t.Name != "" {
+		return _t.Ns == t.Ns && _t.Name == t.Name
+	}
+
+	return _t.Code != "" && _t.Code == t.Code
+}
+
+// ExitCode 命令行退出码, 由错误定义的第三个元素指定, 默认为1
+func (t *KError) ExitCode() int {
+	if t.exit > 0 {
+		return t.exit
+	}
+	return 1
+}
+
+// HTTPStatus 错误对应的HTTP状态码, 没有定义时为500
+func (t *KError) HTTPStatus() int {
+	if t.Status > 0 {
+		return t.Status
+	}
+	return http.StatusInternalServerError
+}
+
+// LogLevel 错误的日志级别, 没有定义时4xx为warn, 其他为error
+func (t *KError) LogLevel() zerolog.Level {
+	if _l, err := zerolog.ParseLevel(t.Level); err == nil && t.Level != "" {
+		return _l
+	}
+	return g.If(t.HTTPStatus() < 500, zerolog.WarnLevel, zerolog.ErrorLevel).(zerolog.Level)
+}
+
+// StackTrace 错误创建时的调用栈
+func (t *KError) StackTrace() []runtime.Frame {
+	var _fs []runtime.Frame
+	_frames := runtime.CallersFrames(t.stack)
+	for {
+		_f, more := _frames.Next()
+		_fs = append(_fs, _f)
+		if !more {
+			break
+		}
+	}
+	return _fs
+}
+
+// Format %+v 输出错误, 调用栈以及完整的cause链
+func (t *KError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		if s.Flag('+') {
+			_, _ = io.WriteString(s, t.Code+": "+t.Msg)
+			for _, _f := range t.StackTrace() {
+				_, _ = fmt.Fprintf(s, "\n\t%s\n\t\t%s:%d", _f.Function, _f.File, _f.Line)
+			}
+			if t.cause != nil {
+				_, _ = fmt.Fprintf(s, "\ncaused by: %+v", t.cause)
+			}
+			return
+		}
+		fallthrough
+	case 's':
+		_, _ = io.WriteString(s, t.Error())
+	case 'q':
+		_, _ = fmt.Fprintf(s, "%q", t.Error())
+	}
+}
+
+// KErrorDef errors.toml中的一条错误定义, Msgs按语言保存错误信息
+// Status, Level, Retryable只能用表格的形式定义, 没有定义时由HTTP状态码推导
+type KErrorDef struct {
+	Ns        string
+	Name      string
+	Code      string
+	Msgs      map[string]string
+	Exit      int
+	Status    int
+	Level     string
+	Retryable bool
+	// File, Line 最先加载的定义所在的位置
+	File string
+	Line int
+
+	tpls map[string]*kmsg.Msg
+	srcs map[string]string
+}
+
+func (t *KErrorDef) location() string {
+	return fmt.Sprintf("%s:%d", t.File, t.Line)
+}
+
+// KErrors 错误信息目录, 每种语言一个文件, 例如errors.zh.toml, errors.en.toml
+// 没有语言后缀的文件(errors.toml)属于DefaultLocale
+type KErrors struct {
+	// DefaultLocale 默认语言, 为空时为zh
+	DefaultLocale string
+	// Fallbacks 找不到对应语言的错误信息时, 依次尝试的语言
+	Fallbacks []string
+	// KnownLocales 文件名后缀可以作为语言的列表, 为空时为ISO 639-1的语言代码
+	// 已经加载的语言, DefaultLocale和Fallbacks总是可以作为语言
+	KnownLocales []string
+
+	data    map[string]map[string]*KErrorDef
+	locales []string
+	ranges  map[string]*kCodeRange
+}
+
+func (t *KErrors) defaultLocale() string {
+	return g.If(t.DefaultLocale == "", "zh", t.DefaultLocale).(string)
+}
+
+// KErrorsProblem 加载错误目录时发现的问题
+type KErrorsProblem struct {
+	File string
+	Line int
+	Ns   string
+	Name string
+	Msg  string
+}
+
+func (t *KErrorsProblem) Error() string {
+	_s := t.File
+	if t.Line > 0 {
+		_s += ":" + strconv.Itoa(t.Line)
+	}
+	if t.Ns != "" {
+		_s += ": " + t.Ns + "." + t.Name
+	}
+	return _s + ": " + t.Msg
+}
+
+// FromPath 加载一个错误文件, 返回该文件的问题, 全部文件加载之后调用Check校验整个错误目录
+// 格式错误的条目不会被加载, 获取时返回未知错误
+func (t *KErrors) FromPath(cfg string) []*KErrorsProblem {
+	_src, err := ioutil.ReadFile(cfg)
+	if err != nil {
+		return []*KErrorsProblem{{File: cfg, Msg: fmt.Sprintf("文件读取失败: %s", err)}}
+	}
+
+	return t.loadFile(cfg, _src)
+}
+
+// FromDir 加载目录下所有的toml文件, 并且校验整个错误目录
+func (t *KErrors) FromDir(dir string) []*KErrorsProblem {
+	_ps := append(t.FromFS(os.DirFS(dir), "*.toml"), t.Check()...)
+	for _, _p := range _ps {
+		if _p.File != "" {
+			_p.File = filepath.Join(dir, _p.File)
+		}
+	}
+	return _ps
+}
+
+// FromFS 加载fsys中所有匹配pattern的toml文件, 返回这些文件的问题, 可以配合embed.FS把错误目录编译进二进制文件
+//
+//	//go:embed errors/*.toml
+//	var errorsFS embed.FS
+//
+//	_ps := append(errs.FromFS(errorsFS, "errors/*.toml"), errs.Check()...)
+func (t *KErrors) FromFS(fsys fs.FS, pattern string) []*KErrorsProblem {
+	_names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return []*KErrorsProblem{{File: pattern, Msg: fmt.Sprintf("文件匹配失败: %s", err)}}
+	}
+
+	var _ps []*KErrorsProblem
+	for _, _name := range _names {
+		_src, err := fs.ReadFile(fsys, _name)
+		if err != nil {
+			_ps = append(_ps, &KErrorsProblem{File: _name, Msg: fmt.Sprintf("文件读取失败: %s", err)})
+			continue
+		}
+		_ps = append(_ps, t.loadFile(_name, _src)...)
+	}
+	return _ps
+}
+
+func (t *KErrors) loadFile(file string, src []byte) []*KErrorsProblem {
+	var _dt map[string]interface{}
+	if _, err := toml.Decode(string(src), &_dt); err != nil {
+		return []*KErrorsProblem{{File: file, Msg: fmt.Sprintf("文件解析失败: %s", err)}}
+	}
+
+	return t.load(file, t.localeOfPath(file), _dt, ktoml.ParseLines(src))
+}
+
+func (t *KErrors) load(file, locale string, dt map[string]interface{}, lines ktoml.Lines) []*KErrorsProblem {
+	var _ps []*KErrorsProblem
+
+	if t.data == nil {
+		t.data = make(map[string]map[string]*KErrorDef)
+	}
+
+	if !containsString(t.locales, locale) {
+		t.locales = append(t.locales, locale)
+	}
+
+	for _, ns := range sortedKeys(dt) {
+		if ns == kErrorsRangesNs {
+			_ps = append(_ps, t.loadRanges(file, dt[ns], lines)...)
+			continue
+		}
+
+		_entries, ok := dt[ns].(map[string]interface{})
+		if !ok {
+			_ps = append(_ps, &KErrorsProblem{File: file, Line: lines.Line(ns), Ns: ns, Msg: "顶层只能是namespace表"})
+			continue
+		}
+
+		if t.data[ns] == nil {
+			t.data[ns] = make(map[string]*KErrorDef)
+		}
+
+		for _, name := range sortedKeys(_entries) {
+			_line := lines.Line(ns, name)
+			_problem := func(format string, args ...interface{}) {
+				_ps = append(_ps, &KErrorsProblem{File: file, Line: _line, Ns: ns, Name: name, Msg: fmt.Sprintf(format, args...)})
+			}
+
+			_e, err := parseKErrorEntry(_entries[name])
+			if err != "" {
+				_problem("%s", err)
+				continue
+			}
+
+			if _, err := strconv.Atoi(_e.Code); err != nil {
+				_problem("code[%s]不是数字", _e.Code)
+			}
+
+			_src := fmt.Sprintf("%s:%d", file, _line)
+			_def := t.data[ns][name]
+			if _def == nil {
+				_def = &KErrorDef{
+					Ns:   ns,
+					Name: name,
+					Code: _e.Code,
+					File: file,
+					Line: _line,
+					Msgs: make(map[string]string),
+					tpls: make(map[string]*kmsg.Msg),
+					srcs: make(map[string]string),
+				}
+				t.data[ns][name] = _def
+			} else if _def.Code != _e.Code {
+				_problem("语言[%s]的code[%s]与%s定义的code[%s]不一致", locale, _e.Code, _def.location(), _def.Code)
+			}
+
+			if _s, ok := _def.srcs[locale]; ok && !strings.HasPrefix(_s, file+":") {
+				_problem("语言[%s]的定义与%s冲突", locale, _s)
+			}
+			_def.srcs[locale] = _src
+
+			_def.Msgs[locale] = _e.Msg
+			_def.tpls[locale] = kmsg.Parse(_e.Msg)
+			if _e.Exit > 0 {
+				_def.Exit = _e.Exit
+			}
+			if _e.Status > 0 {
+				_def.Status = _e.Status
+			}
+			if _e.Level != "" {
+				_def.Level = _e.Level
+			}
+			_def.Retryable = _def.Retryable || _e.Retryable
+		}
+	}
+
+	return _ps
+}
+
+// kErrorEntry 一个语言文件中的一条错误
+type kErrorEntry struct {
+	Code      string
+	Msg       string
+	Exit      int
+	Status    int
+	Level     string
+	Retryable bool
+}
+
+// parseKErrorEntry 解析 ["code", "msg"], ["code", "msg", "exit"]
+// 或者 {code = "code", msg = "msg", status = 404, level = "warn", retryable = true, exit = 2}
+func parseKErrorEntry(v interface{}) (*kErrorEntry, string) {
+	switch _d := v.(type) {
+	case []interface{}:
+		return parseKErrorArray(_d)
+	case map[string]interface{}:
+		return parseKErrorTable(_d)
+	}
+	return nil, "格式错误, 应该是[\"code\", \"msg\"]或者{code = \"code\", msg = \"msg\"}"
+}
+
+func parseKErrorArray(d []interface{}) (*kErrorEntry, string) {
+	if len(d) < 2 || len(d) > 3 {
+		return nil, "格式错误, 应该是[\"code\", \"msg\"]或者[\"code\", \"msg\", \"exit\"]"
+	}
+
+	_ss := make([]string, len(d))
+	for i := range d {
+		switch _v := d[i].(type) {
+		case string:
+			_ss[i] = _v
+		case int64:
+			_ss[i] = strconv.FormatInt(_v, 10)
+		default:
+			return nil, fmt.Sprintf("第%d个元素类型错误: %T", i+1, d[i])
+		}
+	}
+
+	_e := &kErrorEntry{Code: _ss[0], Msg: _ss[1]}
+	if len(_ss) > 2 {
+		_exit, err := strconv.Atoi(_ss[2])
+		if err != nil || _exit < 1 || _exit > 255 {
+			return nil, fmt.Sprintf("exit[%s]应该是1-255之间的数字", _ss[2])
+		}
+		_e.Exit = _exit
+	}
+	return _e, ""
+}
+
+func parseKErrorTable(d map[string]interface{}) (*kErrorEntry, string) {
+	_e := &kErrorEntry{}
+	for _, k := range sortedKeys(d) {
+		_bad := fmt.Sprintf("[%s]类型错误: %T", k, d[k])
+
+		switch k {
+		case "code":
+			switch _v := d[k].(type) {
+			case string:
+				_e.Code = _v
+			case int64:
+				_e.Code = strconv.FormatInt(_v, 10)
+			default:
+				return nil, _bad
+			}
+		case "msg":
+			_v, ok := d[k].(string)
+			if !ok {
+				return nil, _bad
+			}
+			_e.Msg = _v
+		case "exit", "status":
+			_v, ok := d[k].(int64)
+			if !ok {
+				return nil, _bad
+			}
+			if k == "exit" {
+				_e.Exit = int(_v)
+			} else {
+				_e.Status = int(_v)
+			}
+		case "level":
+			_v, ok := d[k].(string)
+			if !ok {
+				return nil, _bad
+			}
+			if _, err := zerolog.ParseLevel(_v); err != nil || _v == "" {
+				return nil, fmt.Sprintf("level[%s]不是合法的日志级别", _v)
+			}
+			_e.Level = _v
+		case "retryable":
+			_v, ok := d[k].(bool)
+			if !ok {
+				return nil, _bad
+			}
+			_e.Retryable = _v
+		default:
+			return nil, fmt.Sprintf("未知的字段[%s]", k)
+		}
+	}
+
+	switch {
+	case _e.Code == "" || _e.Msg == "":
+		return nil, "code和msg不能为空"
+	case _e.Exit != 0 && (_e.Exit < 1 || _e.Exit > 255):
+		return nil, fmt.Sprintf("exit[%d]应该是1-255之间的数字", _e.Exit)
+	case _e.Status != 0 && (_e.Status < 100 || _e.Status > 599):
+		return nil, fmt.Sprintf("status[%d]不是合法的HTTP状态码", _e.Status)
+	}
+	return _e, ""
+}
+
+// Check 校验整个错误目录: 重复的code, 占位符, 格式化参数的数量以及code范围
+func (t *KErrors) Check() []*KErrorsProblem {
+	var _ps []*KErrorsProblem
+
+	_codes := make(map[string]*KErrorDef)
+	for _, ns := range sortedKeys(t.data) {
+		for _, name := range sortedKeys(t.data[ns]) {
+			_def := t.data[ns][name]
+			_problem := func(format string, args ...interface{}) {
+				_ps = append(_ps, &KErrorsProblem{File: _def.File, Line: _def.Line, Ns: ns, Name: name, Msg: fmt.Sprintf(format, args...)})
+			}
+
+			if _d, ok := _codes[_def.Code]; ok {
+				_problem("code[%s]已经被%s的[%s.%s]使用", _def.Code, _d.location(), _d.Ns, _d.Name)
+			} else {
+				_codes[_def.Code] = _def
+			}
+
+			_declared := t.Params(_def)
+			_verbs := -1
+			for _, l := range t.locales {
+				_m, ok := _def.tpls[l]
+				if !ok {
+					continue
+				}
+
+				if _u := _m.Undeclared(_declared); len(_u) > 0 {
+					_problem("语言[%s]使用了未声明的占位符%v", l, _u)
+				}
+
+				_n := countVerbs(_m.String())
+				if _verbs >= 0 && _n != _verbs {
+					_problem("语言[%s]的格式化参数数量%d与其他语言的%d不一致", l, _n, _verbs)
+				}
+				_verbs = _n
+			}
+		}
+	}
+	return append(_ps, t.checkRanges()...)
+}
+
+var fmtVerb = regexp.MustCompile(`%[-+# 0]*(\d+|\*)?(\.(\d+|\*))?[a-zA-Z]`)
+
+// fmtVerbs fmt格式化参数的verb, 忽略%%
+func fmtVerbs(s string) []byte {
+	var _vs []byte
+	for _, _v := range fmtVerb.FindAllString(strings.Replace(s, "%%", "", -1), -1) {
+		_vs = append(_vs, _v[len(_v)-1])
+	}
+	return _vs
+}
+
+// countVerbs 统计fmt格式化参数的数量
+func countVerbs(s string) int {
+	return len(fmtVerbs(s))
+}
+
+// Params 错误信息声明的命名占位符
+// 默认语言的错误信息就是声明, 默认语言缺失时以最先加载的语言为准
+func (t *KErrors) Params(def *KErrorDef) []string {
+	return t.declMsg(def).Names()
+}
+
+func (t *KErrors) declMsg(def *KErrorDef) *kmsg.Msg {
+	if _m, ok := def.tpls[t.defaultLocale()]; ok {
+		return _m
+	}
+
+	for _, l := range t.locales {
+		if _m, ok := def.tpls[l]; ok {
+			return _m
+		}
+	}
+	return kmsg.Parse("")
+}
+
+// Defs 按照namespace和name排序的全部错误定义
+func (t *KErrors) Defs() []*KErrorDef {
+	var _ds []*KErrorDef
+	for _, ns := range sortedKeys(t.data) {
+		for _, name := range sortedKeys(t.data[ns]) {
+			_ds = append(_ds, t.data[ns][name])
+		}
+	}
+	return _ds
+}
+
+// Locales 已经加载的语言
+func (t *KErrors) Locales() []string {
+	return t.locales
+}
+
+// localeChain 查找错误信息时依次尝试的语言, 例如 zh-cn -> zh -> Fallbacks -> DefaultLocale
+func (t *KErrors) localeChain(locale string) []string {
+	var _ls []string
+	_add := func(l string) {
+		if l != "" && !containsString(_ls, l) {
+			_ls = append(_ls, l)
+		}
+	}
+
+	locale = normLocale(locale)
+	_add(locale)
+	if i := strings.Index(locale, "-"); i > 0 {
+		_add(locale[:i])
+	}
+	for _, l := range t.Fallbacks {
+		_add(normLocale(l))
+	}
+	_add(t.defaultLocale())
+	return _ls
+}
+
+func (t *KErrors) msgOf(def *KErrorDef, locale string) *kmsg.Msg {
+	for _, l := range t.localeChain(locale) {
+		if _m, ok := def.tpls[l]; ok {
+			return _m
+		}
+	}
+
+	for _, l := range t.locales {
+		if _m, ok := def.tpls[l]; ok {
+			return _m
+		}
+	}
+	return kmsg.Parse("")
+}
+
+// MatchLocale 从候选语言中(按照优先级排序)选出已加载的语言, 都不支持时返回默认语言
+func (t *KErrors) MatchLocale(candidates ...string) string {
+	for _, c := range candidates {
+		c = normLocale(c)
+		if containsString(t.locales, c) {
+			return c
+		}
+		if i := strings.Index(c, "-"); i > 0 && containsString(t.locales, c[:i]) {
+			return c[:i]
+		}
+	}
+	return t.defaultLocale()
+}
+
+func (t *KErrors) Get(ns, name string, args ...interface{}) *KError {
+	return t.GetLocalized(t.defaultLocale(), ns, name, args...)
+}
+
+// GetLocalized 获取指定语言的错误, 找不到该语言的错误信息时按照localeChain回退
+// args可以是一个map或者struct, 按照名字填充{name}占位符, 也可以按照占位符声明的顺序传入
+// 错误没有定义时返回UnknownCode的错误, 并且打印警告日志
+func (t *KErrors) GetLocalized(locale, ns, name string, args ...interface{}) *KError {
+	_d, ok := t.data[ns][name]
+	if !ok {
+		log.Warn().Str("ns", ns).Str("name", name).Msg("错误没有定义")
+		return newKError(unknownNs, unknownName, UnknownCode, fmt.Sprintf("未知错误[%s.%s]", ns, name))
+	}
+
+	_e := newKError(ns, name, _d.Code, t.render(_d, locale, args))
+	_e.exit = _d.Exit
+	_e.Status = _d.Status
+	_e.Level = _d.Level
+	_e.Retryable = _d.Retryable
+	return _e
+}
+
+// FromEnvelope 把其他kweb服务返回的错误还原成*KError
+// code在错误目录中时带上ns, name, retryable等定义, 信息使用服务端返回的内容
+func (t *KErrors) FromEnvelope(status int, env *ErrorEnvelope) *KError {
+	for _, _def := range t.Defs() {
+		if _def.Code == env.Code {
+			_e := newKError(_def.Ns, _def.Name, _def.Code, env.Msg)
+			_e.exit = _def.Exit
+			_e.Status = g.If(_def.Status > 0, _def.Status, status).(int)
+			_e.Level = _def.Level
+			_e.Retryable = _def.Retryable
+			return _e
+		}
+	}
+
+	_e := newKError("", "", env.Code, env.Msg)
+	_e.Status = status
+	return _e
+}
+
+// render 按照声明的占位符顺序填充位置参数, 所以翻译调整了占位符的顺序也不影响调用方
+func (t *KErrors) render(def *KErrorDef, locale string, args []interface{}) string {
+	_m := t.msgOf(def, locale)
+	if len(_m.Names()) == 0 || len(args) == 0 {
+		return _m.Render(args...)
+	}
+
+	return _m.RenderNamed(kmsg.Values(t.Params(def), args))
+}
+
+// kLocale 文件名中的语言, 例如 en, zh-cn, zh-hans-cn
+var kLocale = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// kISO639 没有配置KnownLocales时可以作为语言的代码
+var kISO639 = strings.Fields(`aa ab ae af ak am an ar as av ay az ba be bg bh bi bm bn bo br bs ca ce ch co cr cs cu cv cy
+da de dv dz ee el en eo es et eu fa ff fi fj fo fr fy ga gd gl gn gu gv ha he hi ho hr ht hu hy hz ia id ie ig ii ik io is
+it iu ja jv ka kg ki kj kk kl km kn ko kr ks ku kv kw ky la lb lg li ln lo lt lu lv mg mh mi mk ml mn mr ms mt my na nb nd
+ne ng nl nn no nr nv ny oc oj om or os pa pi pl ps pt qu rm rn ro ru rw sa sc sd se sg si sk sl sm sn so sq sr ss st su sv
+sw ta te tg th ti tk tl tn to tr ts tt tw ty ug uk ur uz ve vi vo wa wo xh yi yo za zh zu`)
+
+// localeOfPath 从文件名中获取语言, errors.en.toml -> en
+// 后缀不是已知的语言时文件属于DefaultLocale, 例如 user.auth.toml, order.v2.toml
+func (t *KErrors) localeOfPath(cfg string) string {
+	_name := strings.TrimSuffix(filepath.Base(cfg), filepath.Ext(cfg))
+	i := strings.LastIndex(_name, ".")
+	if i <= 0 {
+		return t.defaultLocale()
+	}
+
+	_l := normLocale(_name[i+1:])
+	if !kLocale.MatchString(_l) {
+		return t.defaultLocale()
+	}
+
+	_known := append(append([]string{t.defaultLocale()}, t.locales...), t.Fallbacks...)
+	_known = append(_known, g.If(len(t.KnownLocales) == 0, kISO639, t.KnownLocales).([]string)...)
+	for _, l := range _known {
+		if l = normLocale(l); l == _l || strings.HasPrefix(_l, l+"-") {
+			return _l
+		}
+	}
+	return t.defaultLocale()
+}
+
+func normLocale(l string) string {
+	return strings.ToLower(strings.Replace(strings.TrimSpace(l), "_", "-", -1))
+}
+
+func sortedKeys(m interface{}) []string {
+	var _ks []string
+	for _, _k := range reflect.ValueOf(m).MapKeys() {
+		_ks = append(_ks, _k.String())
+	}
+	sort.Strings(_ks)
+	return _ks
+}
+
+func containsString(ss []string, s string) bool {
+	for _, _s := range ss {
+		if _s == s {
+			return true
+		}
+	}
+	return false
+}
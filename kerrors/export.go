@@ -0,0 +1,108 @@
+package kerrors
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/kooksee/kweb/internal/g"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// kErrorExport 导出给前端和其他服务的错误定义
+type kErrorExport struct {
+	Ns        string            `json:"ns"`
+	Name      string            `json:"name"`
+	Code      string            `json:"code"`
+	Status    int               `json:"status"`
+	Retryable bool              `json:"retryable"`
+	Msgs      map[string]string `json:"msgs"`
+}
+
+// ExportErrors 导出错误目录, format支持json, markdown, ts
+func ExportErrors(errs *KErrors, format string) ([]byte, error) {
+	var _es []*kErrorExport
+	for _, _def := range errs.Defs() {
+		_es = append(_es, &kErrorExport{
+			Ns:        _def.Ns,
+			Name:      _def.Name,
+			Code:      _def.Code,
+			Status:    g.If(_def.Status > 0, _def.Status, http.StatusInternalServerError).(int),
+			Retryable: _def.Retryable,
+			Msgs:      _def.Msgs,
+		})
+	}
+
+	switch format {
+	case "json":
+		return g.Json.MarshalIndent(map[string]interface{}{"locales": errs.Locales(), "errors": _es}, "", "  ")
+	case "markdown", "md":
+		return exportErrorsMarkdown(errs.Locales(), _es), nil
+	case "ts":
+		return exportErrorsTS(_es)
+	}
+	return nil, fmt.Errorf("不支持的导出格式[%s], 可选json, markdown, ts", format)
+}
+
+func exportErrorsMarkdown(locales []string, es []*kErrorExport) []byte {
+	var _b bytes.Buffer
+	_b.WriteString("# 错误码\n\n| Namespace | Name | Code | HTTP | Retryable |")
+	for _, l := range locales {
+		_b.WriteString(" " + l + " |")
+	}
+	_b.WriteString("\n|---|---|---|---|---|" + strings.Repeat("---|", len(locales)) + "\n")
+
+	_cell := strings.NewReplacer("|", "\\|", "\n", "<br>")
+	for _, _e := range es {
+		fmt.Fprintf(&_b, "| %s | %s | %s | %d | %t |", _e.Ns, _e.Name, _e.Code, _e.Status, _e.Retryable)
+		for _, l := range locales {
+			_b.WriteString(" " + _cell.Replace(_e.Msgs[l]) + " |")
+		}
+		_b.WriteString("\n")
+	}
+	return _b.Bytes()
+}
+
+func exportErrorsTS(es []*kErrorExport) ([]byte, error) {
+	var _b bytes.Buffer
+	_b.WriteString("// Code generated by kweb errs export. DO NOT EDIT.\n\n")
+
+	_members := make([]string, len(es))
+	_names := make(map[string]*kErrorExport)
+	for i, _e := range es {
+		_members[i] = goIdent(_e.Ns, true) + goIdent(_e.Name, true)
+		if _d, ok := _names[_members[i]]; ok {
+			return nil, fmt.Errorf("错误[%s.%s]和[%s.%s]生成的枚举名都是%s", _e.Ns, _e.Name, _d.Ns, _d.Name, _members[i])
+		}
+		_names[_members[i]] = _e
+	}
+
+	_b.WriteString("export enum ErrorCode {\n")
+	for i, _e := range es {
+		fmt.Fprintf(&_b, "  %s = %s,\n", _members[i], strconv.Quote(_e.Code))
+	}
+	_b.WriteString("}\n\n")
+
+	_b.WriteString("export type ErrorName =")
+	for _, _e := range es {
+		fmt.Fprintf(&_b, "\n  | %s", strconv.Quote(_e.Ns+"."+_e.Name))
+	}
+	if len(es) == 0 {
+		_b.WriteString(" never")
+	}
+	_b.WriteString(";\n\n")
+
+	_b.WriteString("export interface ErrorDef {\n  code: ErrorCode;\n  status: number;\n  retryable: boolean;\n  msgs: Record<string, string>;\n}\n\n")
+	_b.WriteString("export const Errors: Record<ErrorName, ErrorDef> = {\n")
+	for i, _e := range es {
+		_msgs, err := g.Json.Marshal(_e.Msgs)
+		if err != nil {
+			return nil, err
+		}
+
+		fmt.Fprintf(&_b, "  %s: { code: ErrorCode.%s, status: %d, retryable: %t, msgs: %s },\n",
+			strconv.Quote(_e.Ns+"."+_e.Name), _members[i], _e.Status, _e.Retryable, _msgs)
+	}
+	_b.WriteString("};\n")
+	return _b.Bytes(), nil
+}
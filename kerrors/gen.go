@@ -0,0 +1,134 @@
+package kerrors
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+var kErrorsGenTpl = template.Must(template.New("errs").Parse(`// Code generated by kweb errs gen. DO NOT EDIT.
+
+package {{.Pkg}}
+
+import "github.com/kooksee/kweb/kerrors"
+
+// Errs 生成的错误构造函数使用的错误目录, 启动时加载errors.toml
+var Errs = &kerrors.KErrors{}
+{{range .Funcs}}
+// {{.Name}} {{.Ns}}.{{.ErrName}}: {{.Doc}}
+func {{.Name}}({{range $i, $a := .Args}}{{if $i}}, {{end}}{{$a.Name}} {{$a.Type}}{{end}}) *kerrors.KError {
+	return Errs.Get({{printf "%q" .Ns}}, {{printf "%q" .ErrName}}{{range .Args}}, {{.Name}}{{end}})
+}
+{{end}}`))
+
+type kErrorsGenArg struct {
+	Name string
+	Type string
+}
+
+type kErrorsGenFunc struct {
+	Name    string
+	Ns      string
+	ErrName string
+	Doc     string
+	Args    []kErrorsGenArg
+}
+
+// GenErrors 为错误目录中的每一个错误生成带类型的构造函数
+// 参数来自默认语言的错误信息: 命名占位符按照出现的顺序, 否则按照fmt的verb推导参数类型
+func GenErrors(errs *KErrors, pkg string) ([]byte, error) {
+	var _fs []kErrorsGenFunc
+	_names := make(map[string]*KErrorDef)
+
+	for _, _def := range errs.Defs() {
+		_f := kErrorsGenFunc{
+			Name:    "Err" + goIdent(_def.Ns, true) + goIdent(_def.Name, true),
+			Ns:      _def.Ns,
+			ErrName: _def.Name,
+			Doc:     strings.Replace(errs.declMsg(_def).String(), "\n", " ", -1),
+		}
+
+		if _d, ok := _names[_f.Name]; ok {
+			return nil, fmt.Errorf("错误[%s.%s]和[%s.%s]生成的函数名都是%s", _def.Ns, _def.Name, _d.Ns, _d.Name, _f.Name)
+		}
+		_names[_f.Name] = _def
+
+		if _params := errs.Params(_def); len(_params) > 0 {
+			_args := make(map[string]string)
+			for _, _p := range _params {
+				_a := goIdent(_p, false)
+				if _o, ok := _args[_a]; ok {
+					return nil, fmt.Errorf("错误[%s.%s]的占位符[%s]和[%s]生成的参数名都是%s", _def.Ns, _def.Name, _o, _p, _a)
+				}
+				_args[_a] = _p
+				_f.Args = append(_f.Args, kErrorsGenArg{Name: _a, Type: "interface{}"})
+			}
+		} else if _v := fmtIndexedVerb.FindString(strings.Replace(errs.declMsg(_def).String(), "%%", "", -1)); _v != "" {
+			return nil, fmt.Errorf("错误[%s.%s]使用了带序号的verb[%s], 不能生成参数", _def.Ns, _def.Name, _v)
+		} else {
+			for i, _v := range fmtVerbs(errs.declMsg(_def).String()) {
+				_f.Args = append(_f.Args, kErrorsGenArg{Name: fmt.Sprintf("arg%d", i+1), Type: goTypeOfVerb(_v)})
+			}
+		}
+
+		_fs = append(_fs, _f)
+	}
+
+	var _buf bytes.Buffer
+	if err := kErrorsGenTpl.Execute(&_buf, map[string]interface{}{"Pkg": pkg, "Funcs": _fs}); err != nil {
+		return nil, err
+	}
+	return format.Source(_buf.Bytes())
+}
+
+// fmtIndexedVerb 带序号的verb, 例如 %[1]s, %[2]*d
+var fmtIndexedVerb = regexp.MustCompile(`%[-+# 0-9.*]*\[\d+\]`)
+
+func goTypeOfVerb(v byte) string {
+	switch v {
+	case 's', 'q':
+		return "string"
+	case 'd', 'c', 'o', 'O', 'b', 'U':
+		return "int"
+	case 'f', 'F', 'e', 'E', 'g', 'G':
+		return "float64"
+	case 't':
+		return "bool"
+	default:
+		return "interface{}"
+	}
+}
+
+// goIdent 把error_name, user-id这种名字转换成Go标识符
+func goIdent(s string, exported bool) string {
+	var _b strings.Builder
+	_upper := exported
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			_upper = _b.Len() > 0 || exported
+			continue
+		}
+
+		if _upper {
+			r = unicode.ToUpper(r)
+		} else if _b.Len() == 0 {
+			r = unicode.ToLower(r)
+		}
+		_b.WriteRune(r)
+		_upper = false
+	}
+
+	_id := _b.String()
+	if _id == "" || unicode.IsDigit([]rune(_id)[0]) {
+		_id = "X" + _id
+	}
+	if token.IsKeyword(_id) {
+		_id += "_"
+	}
+	return _id
+}
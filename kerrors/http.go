@@ -0,0 +1,66 @@
+package kerrors
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrorEnvelope 返回给客户端的错误
+type ErrorEnvelope struct {
+	Code string `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// Unknown 把不是*KError的错误包装成UnknownCode的错误, err作为cause
+func Unknown(err error) *KError {
+	return newKError(unknownNs, unknownName, UnknownCode, "服务内部错误").WithCause(err)
+}
+
+// AcceptLanguages 按照q值从高到低解析Accept-Language请求头, q值小于等于0的语言表示不接受, 不返回
+func AcceptLanguages(header string) []string {
+	type _lang struct {
+		tag string
+		q   float64
+	}
+
+	var _ls []_lang
+	for _, _p := range strings.Split(header, ",") {
+		_fs := strings.Split(strings.TrimSpace(_p), ";")
+		if _fs[0] == "" || _fs[0] == "*" {
+			continue
+		}
+
+		_l := _lang{tag: _fs[0], q: 1}
+		for _, _f := range _fs[1:] {
+			if _f = strings.TrimSpace(_f); strings.HasPrefix(_f, "q=") {
+				if _q, err := strconv.ParseFloat(_f[2:], 64); err == nil {
+					_l.q = _q
+				}
+			}
+		}
+		if _l.q <= 0 {
+			continue
+		}
+		_ls = append(_ls, _l)
+	}
+
+	sort.SliceStable(_ls, func(i, j int) bool { return _ls[i].q > _ls[j].q })
+
+	var _tags []string
+	for _, _l := range _ls {
+		_tags = append(_tags, _l.tag)
+	}
+	return _tags
+}
+
+// LocaleOf 根据Accept-Language请求头选出错误目录支持的语言
+func (t *KErrors) LocaleOf(r *http.Request) string {
+	return t.MatchLocale(AcceptLanguages(r.Header.Get("Accept-Language"))...)
+}
+
+// GetFromRequest 获取请求语言对应的错误
+func (t *KErrors) GetFromRequest(r *http.Request, ns, name string, args ...interface{}) *KError {
+	return t.GetLocalized(t.LocaleOf(r), ns, name, args...)
+}
@@ -0,0 +1,164 @@
+package kerrors
+
+import (
+	"fmt"
+	"github.com/kooksee/kweb/internal/ktoml"
+	"strconv"
+)
+
+// kErrorsRangesNs 声明每个namespace拥有的code范围, 例如
+//
+//	[__ranges]
+//	user = ["30000", "30999"]
+//	order = [40000, 40999]
+const kErrorsRangesNs = "__ranges"
+
+type kCodeRange struct {
+	Min  int
+	Max  int
+	File string
+	Line int
+}
+
+func (t *kCodeRange) location() string {
+	return fmt.Sprintf("%s:%d", t.File, t.Line)
+}
+
+func (t *KErrors) loadRanges(file string, v interface{}, lines ktoml.Lines) []*KErrorsProblem {
+	var _ps []*KErrorsProblem
+
+	_dt, ok := v.(map[string]interface{})
+	if !ok {
+		return []*KErrorsProblem{{File: file, Line: lines.Line(kErrorsRangesNs), Msg: "[__ranges]应该是一个表"}}
+	}
+
+	if t.ranges == nil {
+		t.ranges = make(map[string]*kCodeRange)
+	}
+
+	for _, ns := range sortedKeys(_dt) {
+		_r := &kCodeRange{File: file, Line: lines.Line(kErrorsRangesNs, ns)}
+		_problem := func(format string, args ...interface{}) {
+			_ps = append(_ps, &KErrorsProblem{File: file, Line: _r.Line, Ns: kErrorsRangesNs, Name: ns, Msg: fmt.Sprintf(format, args...)})
+		}
+
+		_d, ok := _dt[ns].([]interface{})
+		if !ok || len(_d) != 2 {
+			_problem("格式错误, 应该是[\"min\", \"max\"]")
+			continue
+		}
+
+		var err error
+		if _r.Min, err = codeOf(_d[0]); err != nil {
+			_problem("%s", err)
+			continue
+		}
+		if _r.Max, err = codeOf(_d[1]); err != nil {
+			_problem("%s", err)
+			continue
+		}
+		if _r.Min > _r.Max {
+			_problem("范围[%d, %d]的最小值大于最大值", _r.Min, _r.Max)
+			continue
+		}
+
+		if _o, ok := t.ranges[ns]; ok && (_o.Min != _r.Min || _o.Max != _r.Max) {
+			_problem("范围[%d, %d]与%s声明的[%d, %d]不一致", _r.Min, _r.Max, _o.location(), _o.Min, _o.Max)
+			continue
+		}
+		t.ranges[ns] = _r
+	}
+	return _ps
+}
+
+func codeOf(v interface{}) (int, error) {
+	switch _v := v.(type) {
+	case int64:
+		return int(_v), nil
+	case string:
+		_c, err := strconv.Atoi(_v)
+		if err != nil {
+			return 0, fmt.Errorf("code[%s]不是数字", _v)
+		}
+		return _c, nil
+	}
+	return 0, fmt.Errorf("code类型错误: %T", v)
+}
+
+// checkRanges 检查范围之间没有重叠, 每个错误的code都在所属namespace的范围内, 并且不在其他namespace的范围内
+func (t *KErrors) checkRanges() []*KErrorsProblem {
+	var _ps []*KErrorsProblem
+
+	_nss := sortedKeys(t.ranges)
+	for i, ns := range _nss {
+		_r := t.ranges[ns]
+		for _, _ns := range _nss[i+1:] {
+			if _o := t.ranges[_ns]; _r.Min <= _o.Max && _o.Min <= _r.Max {
+				_ps = append(_ps, &KErrorsProblem{
+					File: _r.File,
+					Line: _r.Line,
+					Ns:   kErrorsRangesNs,
+					Name: ns,
+					Msg:  fmt.Sprintf("范围[%d, %d]与%s的[%s]范围[%d, %d]重叠", _r.Min, _r.Max, _o.location(), _ns, _o.Min, _o.Max),
+				})
+			}
+		}
+	}
+
+	for _, _def := range t.Defs() {
+		_c, err := strconv.Atoi(_def.Code)
+		if err != nil {
+			continue
+		}
+
+		_p := &KErrorsProblem{File: _def.File, Line: _def.Line, Ns: _def.Ns, Name: _def.Name}
+		if _r, ok := t.ranges[_def.Ns]; ok {
+			if _c < _r.Min || _c > _r.Max {
+				_p.Msg = fmt.Sprintf("code[%s]不在%s声明的范围[%d, %d]内", _def.Code, _r.location(), _r.Min, _r.Max)
+				_ps = append(_ps, _p)
+			}
+			continue
+		}
+
+		// 没有声明范围的namespace不能使用其他namespace的范围
+		for _, ns := range _nss {
+			if _r := t.ranges[ns]; _c >= _r.Min && _c <= _r.Max {
+				_p.Msg = fmt.Sprintf("code[%s]在%s声明的[%s]范围[%d, %d]内", _def.Code, _r.location(), ns, _r.Min, _r.Max)
+				_ps = append(_ps, _p)
+				break
+			}
+		}
+	}
+	return _ps
+}
+
+// CodeRange namespace声明的code范围
+func (t *KErrors) CodeRange(ns string) (min, max int, ok bool) {
+	_r, ok := t.ranges[ns]
+	if !ok {
+		return 0, 0, false
+	}
+	return _r.Min, _r.Max, true
+}
+
+// NextCode namespace范围内最小的未使用的code, 其他namespace使用的code也会跳过
+func (t *KErrors) NextCode(ns string) (string, error) {
+	_r, ok := t.ranges[ns]
+	if !ok {
+		return "", fmt.Errorf("namespace[%s]没有在[%s]中声明code范围", ns, kErrorsRangesNs)
+	}
+
+	_used := make(map[int]bool)
+	for _, _def := range t.Defs() {
+		if _c, err := strconv.Atoi(_def.Code); err == nil {
+			_used[_c] = true
+		}
+	}
+
+	for _c := _r.Min; _c <= _r.Max; _c++ {
+		if !_used[_c] {
+			return strconv.Itoa(_c), nil
+		}
+	}
+	return "", fmt.Errorf("namespace[%s]的范围[%d, %d]已经用完", ns, _r.Min, _r.Max)
+}
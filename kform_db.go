@@ -0,0 +1,151 @@
+package kweb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// FormDBTimeout Unique和Exists每次查询的超时时间, 请求的context先结束时以请求的为准
+var FormDBTimeout = 3 * time.Second
+
+// kFormDBDefault 没有指定连接名时使用的数据库连接, 只有一个连接时使用这个连接
+const kFormDBDefault = "default"
+
+// kSQLIdent 表名和列名, 表名可以带schema, 例如 public.users
+var kSQLIdent = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// 查询数据库的校验函数, 最后一个参数是app中数据库连接的名字, 可以省略
+// 字段的值是数组时用一条IN查询校验全部元素
+//
+//	email = ['Unique("users", "email")', "邮箱已经注册"]
+//	category_id = ['Exists("categories", "id", "shop")', "分类不存在"]
+//	tag_ids = ['Exists("tags", "id")', "标签不存在"]
+func init() {
+	RegisterValidator("Unique", func(ctx context.Context, v interface{}, table, column string, db ...string) (bool, string) {
+		n, _, err := kFormDBMatch(ctx, v, table, column, db)
+		if err != nil {
+			return false, err.Error()
+		}
+		return n == 0, fmt.Sprintf("%s.%s中已经存在%d个值", table, column, n)
+	})
+
+	RegisterValidator("Exists", func(ctx context.Context, v interface{}, table, column string, db ...string) (bool, string) {
+		n, _n, err := kFormDBMatch(ctx, v, table, column, db)
+		if err != nil {
+			return false, err.Error()
+		}
+		return n == _n, fmt.Sprintf("%s.%s中有%d个值不存在", table, column, _n-n)
+	})
+}
+
+// kFormDBMatch 查询v或者v中的元素有几个在table.column中存在, 同时返回v中不同值的个数
+// 每个值在数据库中单独比较, 类型转换和字符集的排序规则都以数据库为准, 例如1和"1", 不区分大小写时的"A"和"a"
+func kFormDBMatch(ctx context.Context, v interface{}, table, column string, db []string) (int, int, error) {
+	if !kSQLIdent.MatchString(table) || !kSQLIdent.MatchString(column) {
+		return 0, 0, fmt.Errorf("表名[%s]或者列名[%s]格式错误", table, column)
+	}
+
+	_vs, err := kFormDBValues(v)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(_vs) == 0 {
+		return 0, 0, nil
+	}
+
+	_name := ""
+	if len(db) > 0 {
+		_name = db[0]
+	}
+	_db, err := GetApp().formDB(_name)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_cols := make([]string, len(_vs))
+	for i := range _vs {
+		_cols[i] = fmt.Sprintf("MAX(CASE WHEN %s = ? THEN 1 ELSE 0 END)", column)
+	}
+	_q, _args, err := sqlx.In(
+		fmt.Sprintf("SELECT %s FROM %s WHERE %s IN (?)", strings.Join(_cols, ", "), table, column),
+		append(append([]interface{}{}, _vs...), _vs)...,
+	)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, FormDBTimeout)
+	defer cancel()
+
+	_ms := make([]sql.NullInt64, len(_vs))
+	_dest := make([]interface{}, len(_vs))
+	for i := range _ms {
+		_dest[i] = &_ms[i]
+	}
+	if err := _db.QueryRowContext(ctx, _db.Rebind(_q), _args...).Scan(_dest...); err != nil {
+		return 0, 0, fmt.Errorf("查询%s.%s失败: %s", table, column, err)
+	}
+
+	_n := 0
+	for _, _m := range _ms {
+		if _m.Valid && _m.Int64 == 1 {
+			_n++
+		}
+	}
+	return _n, len(_vs), nil
+}
+
+// kFormDBValues 把字段的值转换成查询参数并且去重, 没有小数部分的数字转换成int64
+func kFormDBValues(v interface{}) ([]interface{}, error) {
+	_a, ok := v.([]interface{})
+	if !ok {
+		_a = []interface{}{v}
+	}
+
+	var _vs []interface{}
+	_seen := make(map[interface{}]bool)
+	for _, e := range _a {
+		switch _e := e.(type) {
+		case float64:
+			if _e == math.Trunc(_e) && math.Abs(_e) < 1<<53 {
+				e = int64(_e)
+			}
+		case string, bool, int64:
+		case nil:
+			continue
+		default:
+			return nil, fmt.Errorf("类型%T不能用于数据库查询", e)
+		}
+
+		if !_seen[e] {
+			_seen[e] = true
+			_vs = append(_vs, e)
+		}
+	}
+	return _vs, nil
+}
+
+// formDB 表单校验使用的数据库连接, name为空时使用default, 只有一个连接时使用这个连接
+func (t *app) formDB(name string) (*sqlx.DB, error) {
+	if name == "" && len(t.dbs) == 1 {
+		for _, _db := range t.dbs {
+			return _db, nil
+		}
+	}
+
+	if name == "" {
+		name = kFormDBDefault
+	}
+
+	_db, ok := t.dbs[name]
+	if !ok {
+		return nil, fmt.Errorf("数据库连接[%s]不存在", name)
+	}
+	return _db, nil
+}
@@ -0,0 +1,63 @@
+package kweb
+
+import (
+	"github.com/kooksee/kweb/internal/validator"
+	"strconv"
+)
+
+// kFormPath 字段路径, 例如 address.city, items[*].qty, tags[], items[0].name
+type kFormPath = validator.Path
+
+// kFormValue 按照路径取到的值, path为具体的路径, 例如 items[3].qty
+// set修改文档中对应的值, 上一级不存在时为nil
+type kFormValue struct {
+	path  string
+	value interface{}
+	ok    bool
+	set   func(interface{})
+}
+
+// resolveKFormPath 按照路径取值, 通配符展开成数组的每个元素
+// 路径中间的值不存在时返回一个ok为false的值, 通配符对应的值不是数组时没有元素
+func resolveKFormPath(t kFormPath, doc interface{}) []kFormValue {
+	_vs := []kFormValue{{value: doc, ok: true}}
+	for _, _s := range t {
+		var _next []kFormValue
+		for _, _v := range _vs {
+			switch {
+			case _s.Wildcard:
+				_a, _ := _v.value.([]interface{})
+				for i, e := range _a {
+					_next = append(_next, kFormValue{path: _v.path + "[" + strconv.Itoa(i) + "]", value: e, ok: true, set: kFormSetIndex(_a, i)})
+				}
+			case _s.Key != "":
+				_n := kFormValue{path: _s.Key}
+				if _v.path != "" {
+					_n.path = _v.path + "." + _s.Key
+				}
+				if _m, ok := _v.value.(map[string]interface{}); ok && _v.ok {
+					_n.value, _n.ok = _m[_s.Key]
+					_n.set = kFormSetKey(_m, _s.Key)
+				}
+				_next = append(_next, _n)
+			default:
+				_n := kFormValue{path: _v.path + "[" + strconv.Itoa(_s.Index) + "]"}
+				if _a, ok := _v.value.([]interface{}); ok && _v.ok && _s.Index < len(_a) {
+					_n.value, _n.ok = _a[_s.Index], true
+					_n.set = kFormSetIndex(_a, _s.Index)
+				}
+				_next = append(_next, _n)
+			}
+		}
+		_vs = _next
+	}
+	return _vs
+}
+
+func kFormSetKey(m map[string]interface{}, key string) func(interface{}) {
+	return func(v interface{}) { m[key] = v }
+}
+
+func kFormSetIndex(a []interface{}, i int) func(interface{}) {
+	return func(v interface{}) { a[i] = v }
+}
@@ -0,0 +1,117 @@
+package kweb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// kRuleCall 规则中的一次函数调用, 例如 Len(3, 32)
+type kRuleCall struct {
+	Name string
+	Args []interface{}
+}
+
+// parseKRuleCalls 把 A(1) && B("x") 这种只由函数调用和&&组成的规则拆成调用列表
+// 参数只支持数字, 字符串和true/false, 其他形式的规则返回false
+func parseKRuleCalls(rule string) ([]*kRuleCall, bool) {
+	var _cs []*kRuleCall
+	for _, _part := range splitKRule(rule, "&&") {
+		_part = strings.TrimSpace(_part)
+
+		i := strings.Index(_part, "(")
+		if i <= 0 || !strings.HasSuffix(_part, ")") || !isKIdent(_part[:i]) {
+			return nil, false
+		}
+
+		_c := &kRuleCall{Name: _part[:i]}
+		if _args := strings.TrimSpace(_part[i+1 : len(_part)-1]); _args != "" {
+			for _, _a := range splitKRule(_args, ",") {
+				_v, ok := parseKRuleLiteral(strings.TrimSpace(_a))
+				if !ok {
+					return nil, false
+				}
+				_c.Args = append(_c.Args, _v)
+			}
+		}
+		_cs = append(_cs, _c)
+	}
+	return _cs, len(_cs) > 0
+}
+
+// splitKRule 按照sep拆分, 忽略引号和括号中的sep
+func splitKRule(s, sep string) []string {
+	var _ps []string
+	var _quote byte
+	_depth, _start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case _quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == _quote {
+				_quote = 0
+			}
+		case c == '"' || c == '\'':
+			_quote = c
+		case c == '(' || c == '[':
+			_depth++
+		case c == ')' || c == ']':
+			_depth--
+		case _depth == 0 && strings.HasPrefix(s[i:], sep):
+			_ps = append(_ps, s[_start:i])
+			_start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(_ps, s[_start:])
+}
+
+func parseKRuleLiteral(s string) (interface{}, bool) {
+	switch {
+	case s == "true" || s == "false":
+		return s == "true", true
+	case strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'") && len(s) >= 2:
+		_v, err := strconv.Unquote(`"` + strings.Replace(s[1:len(s)-1], `"`, `\"`, -1) + `"`)
+		return _v, err == nil
+	case strings.HasPrefix(s, `"`):
+		_v, err := strconv.Unquote(s)
+		return _v, err == nil
+	}
+
+	_f, err := strconv.ParseFloat(s, 64)
+	return _f, err == nil
+}
+
+func isKIdent(s string) bool {
+	for i, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// kRuleLiteral 把参数写成规则中的字面量
+func kRuleLiteral(v interface{}) string {
+	switch _v := v.(type) {
+	case string:
+		return strconv.Quote(_v)
+	case float64:
+		return strconv.FormatFloat(_v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(_v, 10)
+	case bool:
+		return strconv.FormatBool(_v)
+	}
+	return strconv.Quote(fmt.Sprint(v))
+}
+
+// kRuleCallString 把调用写成规则
+func kRuleCallString(name string, args ...interface{}) string {
+	_as := make([]string, len(args))
+	for i, _a := range args {
+		_as[i] = kRuleLiteral(_a)
+	}
+	return name + "(" + strings.Join(_as, ", ") + ")"
+}
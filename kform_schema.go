@@ -0,0 +1,671 @@
+package kweb
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/kooksee/kweb/internal/g"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	kSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+	// kSchemaRules, kSchemaDecl 保存原始规则和字段声明的扩展字段, 导入时优先使用, 保证可以原样转换回来
+	kSchemaRules = "x-kweb-rules"
+	kSchemaDecl  = "x-kweb-field"
+)
+
+// kSchemaFormats 校验函数和JSON Schema format的对应关系
+var kSchemaFormats = map[string]string{
+	"IsEmail":           "email",
+	"IsURL":             "uri",
+	"IsURI":             "uri-reference",
+	"IsUUID":            "uuid",
+	"IsUUIDRFC4122":     "uuid",
+	"IsIPv4":            "ipv4",
+	"IsIPv6":            "ipv6",
+	"IsHostnameRFC1123": "hostname",
+}
+
+// kSchemaStringFuncs 只能用于字符串的校验函数, 用来推断字段的类型
+var kSchemaStringFuncs = map[string]bool{
+	"Match": true, "IsAlpha": true, "IsAlphanum": true, "IsAlphanumUnicode": true, "IsNumeric": true,
+	"IsNumber": true, "IsHexadecimal": true, "IsASCII": true, "IsPrintableASCII": true, "IsBase64": true,
+}
+
+// kSchema 生成JSON Schema时的中间结构
+type kSchema struct {
+	typ        string
+	format     string
+	nullable   bool
+	dflt       interface{}
+	hasDefault bool
+	props      map[string]*kSchema
+	items      *kSchema
+	required   []string
+	calls      []*kRuleCall
+	rules      []map[string]interface{}
+	decl       map[string]interface{}
+}
+
+func (t *kSchema) child(key string) *kSchema {
+	if t.props == nil {
+		t.props = make(map[string]*kSchema)
+	}
+	if t.props[key] == nil {
+		t.props[key] = &kSchema{}
+	}
+	return t.props[key]
+}
+
+// locate 按照字段路径找到对应的节点, 返回节点, 所在的对象和键, 数组元素没有所在的对象
+func (t *kSchema) locate(path kFormPath) (node, parent *kSchema, key string) {
+	node = t
+	for _, _s := range path {
+		if _s.Key != "" {
+			parent, key = node, _s.Key
+			node = node.child(_s.Key)
+			continue
+		}
+
+		if node.items == nil {
+			node.items = &kSchema{}
+		}
+		parent, key, node = nil, "", node.items
+	}
+	return
+}
+
+// kind 字段的类型, 没有声明时根据结构和校验函数推断, 推断不出来时为空
+func (t *kSchema) kind() string {
+	switch {
+	case t.typ != "":
+		return t.typ
+	case t.props != nil:
+		return "object"
+	case t.items != nil:
+		return "array"
+	}
+
+	for _, _c := range t.calls {
+		if _c.Name == "IsType" && len(_c.Args) == 1 {
+			if _t, ok := _c.Args[0].(string); ok {
+				return _t
+			}
+		}
+	}
+	for _, _c := range t.calls {
+		if kSchemaStringFuncs[_c.Name] || kSchemaFormats[_c.Name] != "" {
+			return "string"
+		}
+	}
+	return ""
+}
+
+func (t *kSchema) render() map[string]interface{} {
+	_m := make(map[string]interface{})
+	_kind := t.kind()
+
+	switch {
+	case _kind != "" && t.nullable:
+		_m["type"] = []interface{}{_kind, "null"}
+	case _kind != "":
+		_m["type"] = _kind
+	}
+
+	if t.format != "" {
+		_m["format"] = t.format
+	}
+	if t.hasDefault {
+		_m["default"] = t.dflt
+	}
+
+	if t.props != nil {
+		_ps := make(map[string]interface{})
+		for k, v := range t.props {
+			_ps[k] = v.render()
+		}
+		_m["properties"] = _ps
+	}
+	if len(t.required) > 0 {
+		_m["required"] = t.required
+	}
+	if t.items != nil {
+		_m["items"] = t.items.render()
+	}
+
+	for _, _c := range t.calls {
+		applyKSchemaCall(_m, _kind, _c)
+	}
+
+	if len(t.rules) > 0 {
+		_m[kSchemaRules] = t.rules
+	}
+	if len(t.decl) > 0 {
+		_m[kSchemaDecl] = t.decl
+	}
+	return _m
+}
+
+// kSchemaBounds 同一个约束在不同类型下的关键字: number, string, array, object
+var kSchemaBounds = map[string][4]string{
+	"min": {"minimum", "minLength", "minItems", "minProperties"},
+	"max": {"maximum", "maxLength", "maxItems", "maxProperties"},
+}
+
+func isKSchemaNumber(kind string) bool {
+	return kind == "number" || kind == "integer"
+}
+
+// setKSchemaBound 设置约束, JSON Schema中关键字只对对应的类型生效
+// 类型未知时大小的约束设置number和string的关键字, 长度的约束(lengthOnly)设置string和array的关键字
+func setKSchemaBound(m map[string]interface{}, kind, bound string, v float64, lengthOnly bool) {
+	for i, _kind := range []string{"number", "string", "array", "object"} {
+		switch {
+		case i == 0 && lengthOnly:
+			continue
+		case kind == "" && (i == 3 || i == 2 && !lengthOnly):
+			continue
+		case kind != "" && !(_kind == kind || i == 0 && isKSchemaNumber(kind)):
+			continue
+		}
+
+		// 长度只能是整数
+		_v := v
+		if i > 0 && bound == "min" {
+			_v = math.Ceil(v)
+		} else if i > 0 {
+			_v = math.Floor(v)
+		}
+		m[kSchemaBounds[bound][i]] = _v
+	}
+}
+
+func applyKSchemaCall(m map[string]interface{}, kind string, c *kRuleCall) {
+	_num := func(i int) (float64, bool) {
+		if i >= len(c.Args) {
+			return 0, false
+		}
+		_f, ok := c.Args[i].(float64)
+		return _f, ok
+	}
+
+	switch c.Name {
+	case "Gte", "Min", "IsGte", "HasMinOf":
+		if n, ok := _num(0); ok {
+			setKSchemaBound(m, kind, "min", n, false)
+		}
+	case "Lte", "Max", "IsLte", "HasMaxOf":
+		if n, ok := _num(0); ok {
+			setKSchemaBound(m, kind, "max", n, false)
+		}
+	case "Gt", "IsGt":
+		if n, ok := _num(0); ok {
+			if kind == "" || isKSchemaNumber(kind) {
+				m["exclusiveMinimum"] = n
+			}
+			if !isKSchemaNumber(kind) {
+				setKSchemaBound(m, kind, "min", math.Floor(n)+1, true)
+			}
+		}
+	case "Lt", "IsLt":
+		if n, ok := _num(0); ok {
+			if kind == "" || isKSchemaNumber(kind) {
+				m["exclusiveMaximum"] = n
+			}
+			if !isKSchemaNumber(kind) {
+				setKSchemaBound(m, kind, "max", math.Ceil(n)-1, true)
+			}
+		}
+	case "Len", "HasLengthOf":
+		_min, ok := _num(0)
+		if !ok || isKSchemaNumber(kind) {
+			return
+		}
+		_max := _min
+		if n, ok := _num(1); ok {
+			_max = n
+		}
+		setKSchemaBound(m, kind, "min", _min, true)
+		setKSchemaBound(m, kind, "max", _max, true)
+	case "Eq", "IsEq":
+		if len(c.Args) == 1 {
+			m["const"] = c.Args[0]
+		}
+	case "OneOf":
+		m["enum"] = c.Args
+	case "Match":
+		if len(c.Args) == 1 {
+			m["pattern"] = c.Args[0]
+		}
+	default:
+		if _f := kSchemaFormats[c.Name]; _f != "" {
+			m["format"] = _f
+		}
+	}
+}
+
+// FormSchema 生成表单的JSON Schema(draft 2020-12)
+// 可以转换的规则生成对应的关键字, 全部规则原样保存在x-kweb-rules中
+func (t *KForms) FormSchema(form string) map[string]interface{} {
+	_rules, ok := t.data[form]
+	g.AssertBool(!ok, "表单[%s]不存在", form)
+
+	_root := &kSchema{typ: "object", props: make(map[string]*kSchema)}
+	for _, _r := range _rules {
+		_rule := map[string]interface{}{"rule": _r.Rule, "msg": _r.Msg}
+		if _r.Code != "" {
+			_rule["code"] = _r.Code
+		}
+
+		if _r.isDoc() {
+			_rule["field"] = _r.Field
+			_rule["phase"] = _r.Phase
+			_root.rules = append(_root.rules, _rule)
+			continue
+		}
+
+		_node, _parent, _key := _root.locate(_r.path)
+		if !_r.isDecl() {
+			_node.rules = append(_node.rules, _rule)
+			if _cs, ok := parseKRuleCalls(_r.Rule); ok {
+				_node.calls = append(_node.calls, _cs...)
+			}
+			continue
+		}
+
+		if _r.Required && _parent != nil && !containsString(_parent.required, _key) {
+			_parent.required = append(_parent.required, _key)
+		}
+
+		_node.decl = make(map[string]interface{})
+		for k, v := range map[string]bool{"required": _r.Required, "nullable": _r.Nullable, "optional": _r.Optional} {
+			if v {
+				_node.decl[k] = true
+			}
+		}
+		if len(_r.Transform) > 0 {
+			_node.decl["transform"] = _r.Transform
+		}
+		if containsString(_r.Transform, "to_time") {
+			_node.decl["layout"] = _r.Layout
+		}
+		if _r.hasDefault {
+			_node.decl["default"] = _r.Default
+		}
+
+		_node.nullable = _r.Nullable
+		_node.dflt, _node.hasDefault = _r.Default, _r.hasDefault
+		for _, _t := range _r.Transform {
+			switch _t {
+			case "to_int":
+				_node.typ = "integer"
+			case "to_float":
+				_node.typ = "number"
+			case "to_bool":
+				_node.typ = "boolean"
+			case "to_time":
+				_node.typ, _node.format = "string", g.If(_r.Layout == "2006-01-02", "date", "date-time").(string)
+			default:
+				_node.typ = "string"
+			}
+		}
+	}
+
+	_m := _root.render()
+	_m["$schema"] = kSchemaDraft
+	_m["title"] = form
+	return _m
+}
+
+// ExportFormSchema 导出表单的JSON Schema, form为空时导出全部表单, 每个表单是$defs中的一项
+func ExportFormSchema(forms *KForms, form string) ([]byte, error) {
+	if form != "" {
+		if _, ok := forms.data[form]; !ok {
+			return nil, fmt.Errorf("表单[%s]不存在", form)
+		}
+		return g.Json.MarshalIndent(forms.FormSchema(form), "", "  ")
+	}
+
+	_defs := make(map[string]interface{})
+	for _, _f := range forms.Forms() {
+		_s := forms.FormSchema(_f)
+		delete(_s, "$schema")
+		_defs[_f] = _s
+	}
+	return g.Json.MarshalIndent(map[string]interface{}{"$schema": kSchemaDraft, "$defs": _defs}, "", "  ")
+}
+
+// ImportFormSchema 把JSON Schema转换成表单的toml定义
+// 有$defs时每一项是一个表单, 否则整个文档是一个表单, 表单名为form, form为空时使用title
+// 有x-kweb-rules时原样使用其中的规则, 否则根据关键字生成规则
+func ImportFormSchema(dt []byte, form string) ([]byte, error) {
+	var _s map[string]interface{}
+	if err := g.Json.Unmarshal(dt, &_s); err != nil {
+		return nil, fmt.Errorf("JSON Schema解析失败: %s", err)
+	}
+
+	_forms := make(map[string]map[string]interface{})
+	if _defs, ok := _s["$defs"].(map[string]interface{}); ok && _s["properties"] == nil {
+		for k, v := range _defs {
+			if _d, ok := v.(map[string]interface{}); ok && (form == "" || form == k) {
+				_forms[k] = _d
+			}
+		}
+	} else {
+		if form == "" {
+			form, _ = _s["title"].(string)
+		}
+		if form == "" {
+			return nil, fmt.Errorf("JSON Schema没有title, 需要指定表单名")
+		}
+		_forms[form] = _s
+	}
+
+	if len(_forms) == 0 {
+		return nil, fmt.Errorf("JSON Schema中没有表单")
+	}
+
+	var _b bytes.Buffer
+	_b.WriteString("# Code generated by kweb form import.\n")
+	for _, _name := range sortedKeys(_forms) {
+		var _fs kSchemaFields
+		_fs.walk("", _forms[_name], false)
+
+		for _, _r := range kSchemaSpecs(_forms[_name][kSchemaRules]) {
+			_f, _ := _r["field"].(string)
+			if strings.HasPrefix(_f, "__") {
+				delete(_r, "field")
+				_fs.add(_f, _r)
+			}
+		}
+
+		_b.WriteString("\n[" + tomlKeyPath(strings.Split(_name, ".")) + "]\n")
+		for _, _f := range _fs.order {
+			_b.WriteString(tomlKey(_f) + " = [")
+			for i, _spec := range _fs.specs[_f] {
+				if i > 0 {
+					_b.WriteString(", ")
+				}
+				_b.WriteString(tomlInlineTable(_spec))
+			}
+			_b.WriteString("]\n")
+		}
+	}
+	return _b.Bytes(), nil
+}
+
+// kSchemaFields 导入时按照字段收集规则
+type kSchemaFields struct {
+	order []string
+	specs map[string][]map[string]interface{}
+}
+
+func (t *kSchemaFields) add(field string, spec map[string]interface{}) {
+	if t.specs == nil {
+		t.specs = make(map[string][]map[string]interface{})
+	}
+	if _, ok := t.specs[field]; !ok {
+		t.order = append(t.order, field)
+	}
+
+	// 不同类型的关键字可能生成相同的规则, 例如minimum和minLength都生成Gte
+	for _, _s := range t.specs[field] {
+		if _s["rule"] != nil && _s["rule"] == spec["rule"] {
+			return
+		}
+	}
+	t.specs[field] = append(t.specs[field], spec)
+}
+
+func (t *kSchemaFields) walk(path string, s map[string]interface{}, required bool) {
+	if path != "" {
+		_decl, _ := s[kSchemaDecl].(map[string]interface{})
+		if _decl == nil {
+			_decl = make(map[string]interface{})
+			if required {
+				_decl["required"] = true
+			}
+			if _ts, ok := s["type"].([]interface{}); ok {
+				for _, _t := range _ts {
+					if _t == "null" {
+						_decl["nullable"] = true
+					}
+				}
+			}
+			if _d, ok := s["default"]; ok && isTomlScalar(_d) {
+				_decl["default"] = _d
+			}
+		}
+		if len(_decl) > 0 {
+			t.add(path, _decl)
+		}
+
+		if _rs := kSchemaSpecs(s[kSchemaRules]); len(_rs) > 0 {
+			for _, _r := range _rs {
+				t.add(path, _r)
+			}
+		} else {
+			for _, _r := range kSchemaKeywordRules(s) {
+				t.add(path, _r)
+			}
+		}
+	}
+
+	if _ps, ok := s["properties"].(map[string]interface{}); ok {
+		_req := make(map[string]bool)
+		if _rs, ok := s["required"].([]interface{}); ok {
+			for _, _r := range _rs {
+				if _k, ok := _r.(string); ok {
+					_req[_k] = true
+				}
+			}
+		}
+
+		for _, k := range sortedKeys(_ps) {
+			if _p, ok := _ps[k].(map[string]interface{}); ok {
+				t.walk(strings.TrimPrefix(path+"."+k, "."), _p, _req[k])
+			}
+		}
+	}
+
+	if _items, ok := s["items"].(map[string]interface{}); ok && path != "" {
+		t.walk(path+"[*]", _items, false)
+	}
+}
+
+// kSchemaSpecs 读取x-kweb-rules
+func kSchemaSpecs(v interface{}) []map[string]interface{} {
+	_a, _ := v.([]interface{})
+
+	var _rs []map[string]interface{}
+	for _, _e := range _a {
+		_m, ok := _e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		_r := make(map[string]interface{})
+		for _, k := range []string{"field", "rule", "msg", "code", "phase"} {
+			if _v, ok := _m[k].(string); ok && (_v != "" || k == "msg") {
+				_r[k] = _v
+			}
+		}
+		if _r["rule"] != nil {
+			_rs = append(_rs, _r)
+		}
+	}
+	return _rs
+}
+
+// kSchemaKeywordRules 根据JSON Schema的关键字生成规则
+func kSchemaKeywordRules(s map[string]interface{}) []map[string]interface{} {
+	var _rs []map[string]interface{}
+	_add := func(msg, name string, args ...interface{}) {
+		_rs = append(_rs, map[string]interface{}{"rule": kRuleCallString(name, args...), "msg": msg})
+	}
+
+	_num := func(k string) (float64, bool) {
+		_f, ok := s[k].(float64)
+		return _f, ok
+	}
+
+	// type转换成IsType, 其他关键字只对对应类型的值生效, 例如minimum不限制字符串
+	var _types []interface{}
+	switch _t := s["type"].(type) {
+	case string:
+		_types = append(_types, _t)
+	case []interface{}:
+		for _, _e := range _t {
+			if _e != "null" {
+				_types = append(_types, _e)
+			}
+		}
+	}
+	if len(_types) > 0 && _types[0] != "null" {
+		_add(fmt.Sprintf("{field}的类型必须是%s", kSchemaTypeNames(_types)), "IsType", _types...)
+	}
+
+	for _, _ks := range [][2]string{{"minLength", "maxLength"}, {"minItems", "maxItems"}, {"minProperties", "maxProperties"}} {
+		_min, _hasMin := _num(_ks[0])
+		_max, _hasMax := _num(_ks[1])
+		switch {
+		case _hasMin && _hasMax:
+			_add(fmt.Sprintf("{field}长度必须在%v到%v之间", _min, _max), "Len", _min, _max)
+		case _hasMin:
+			_add(fmt.Sprintf("{field}长度不能小于%v", _min), "Gte", _min)
+		case _hasMax:
+			_add(fmt.Sprintf("{field}长度不能大于%v", _max), "Lte", _max)
+		}
+	}
+
+	if n, ok := _num("minimum"); ok {
+		_add(fmt.Sprintf("{field}不能小于%v", n), "Gte", n)
+	}
+	if n, ok := _num("maximum"); ok {
+		_add(fmt.Sprintf("{field}不能大于%v", n), "Lte", n)
+	}
+	if n, ok := _num("exclusiveMinimum"); ok {
+		_add(fmt.Sprintf("{field}必须大于%v", n), "Gt", n)
+	}
+	if n, ok := _num("exclusiveMaximum"); ok {
+		_add(fmt.Sprintf("{field}必须小于%v", n), "Lt", n)
+	}
+	if _p, ok := s["pattern"].(string); ok {
+		_add("{field}格式错误", "Match", _p)
+	}
+	if _e, ok := s["enum"].([]interface{}); ok && len(_e) > 0 {
+		_add("{field}取值错误", "OneOf", _e...)
+	}
+	if _c, ok := s["const"]; ok && isTomlScalar(_c) {
+		_add("{field}取值错误", "Eq", _c)
+	}
+
+	if _f, ok := s["format"].(string); ok {
+		_names := make([]string, 0, len(kSchemaFormats))
+		for _n := range kSchemaFormats {
+			_names = append(_names, _n)
+		}
+		sort.Strings(_names)
+
+		for _, _n := range _names {
+			if kSchemaFormats[_n] == _f {
+				_add("{field}不是有效的"+_f, _n)
+				break
+			}
+		}
+	}
+	return _rs
+}
+
+func kSchemaTypeNames(types []interface{}) string {
+	_ns := make([]string, len(types))
+	for i, _t := range types {
+		_ns[i] = fmt.Sprint(_t)
+	}
+	return strings.Join(_ns, "或者")
+}
+
+func isTomlScalar(v interface{}) bool {
+	switch v.(type) {
+	case string, float64, bool:
+		return true
+	}
+	return false
+}
+
+// tomlString toml的基本字符串
+func tomlString(s string) string {
+	var _b strings.Builder
+	_b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			_b.WriteByte('\\')
+			_b.WriteRune(r)
+		case r == '\n':
+			_b.WriteString(`\n`)
+		case r == '\t':
+			_b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&_b, `\u%04X`, r)
+		default:
+			_b.WriteRune(r)
+		}
+	}
+	_b.WriteByte('"')
+	return _b.String()
+}
+
+// tomlKey 不是裸键时加上引号
+func tomlKey(k string) string {
+	for _, r := range k {
+		if !(r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return tomlString(k)
+		}
+	}
+	return g.If(k == "", `""`, k).(string)
+}
+
+func tomlKeyPath(ks []string) string {
+	_ks := make([]string, len(ks))
+	for i, k := range ks {
+		_ks[i] = tomlKey(k)
+	}
+	return strings.Join(_ks, ".")
+}
+
+func tomlValue(v interface{}) string {
+	switch _v := v.(type) {
+	case string:
+		return tomlString(_v)
+	case bool:
+		return strconv.FormatBool(_v)
+	case float64:
+		if _v == math.Trunc(_v) && math.Abs(_v) < 1e15 {
+			return strconv.FormatInt(int64(_v), 10)
+		}
+		return strconv.FormatFloat(_v, 'g', -1, 64)
+	case []interface{}:
+		_vs := make([]string, len(_v))
+		for i := range _v {
+			_vs[i] = tomlValue(_v[i])
+		}
+		return "[" + strings.Join(_vs, ", ") + "]"
+	}
+	return tomlString(fmt.Sprint(v))
+}
+
+// tomlInlineTable 按照固定的顺序输出内联表
+func tomlInlineTable(m map[string]interface{}) string {
+	var _ps []string
+	for _, k := range []string{"required", "nullable", "optional", "transform", "layout", "default", "rule", "msg", "code", "phase"} {
+		if _v, ok := m[k]; ok {
+			_ps = append(_ps, k+" = "+tomlValue(_v))
+		}
+	}
+	return "{" + strings.Join(_ps, ", ") + "}"
+}
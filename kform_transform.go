@@ -0,0 +1,132 @@
+package kweb
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// kFormTransforms 字段声明中可以使用的转换, 在规则执行之前按照声明的顺序执行
+//
+//	trim, lower, upper  字符串处理
+//	to_int              转换成int64, 支持数字字符串和没有小数部分的数字
+//	to_float            转换成float64
+//	to_bool             转换成bool, 支持true/false/1/0等字符串和数字
+//	to_time             转换成time.Time, 字符串按照layout(默认RFC3339)解析, 数字为unix秒
+var kFormTransforms = map[string]func(v interface{}, layout string) (interface{}, error){
+	"trim": func(v interface{}, _ string) (interface{}, error) {
+		return kFormString(v, strings.TrimSpace)
+	},
+	"lower": func(v interface{}, _ string) (interface{}, error) {
+		return kFormString(v, strings.ToLower)
+	},
+	"upper": func(v interface{}, _ string) (interface{}, error) {
+		return kFormString(v, strings.ToUpper)
+	},
+	"to_int": func(v interface{}, _ string) (interface{}, error) {
+		switch _v := v.(type) {
+		case string:
+			return strconv.ParseInt(strings.TrimSpace(_v), 10, 64)
+		case float64:
+			if _v != math.Trunc(_v) {
+				return nil, fmt.Errorf("%v不是整数", _v)
+			}
+			return int64(_v), nil
+		case int64:
+			return _v, nil
+		}
+		return nil, fmt.Errorf("类型%T不能转换成整数", v)
+	},
+	"to_float": func(v interface{}, _ string) (interface{}, error) {
+		switch _v := v.(type) {
+		case string:
+			return strconv.ParseFloat(strings.TrimSpace(_v), 64)
+		case float64:
+			return _v, nil
+		case int64:
+			return float64(_v), nil
+		}
+		return nil, fmt.Errorf("类型%T不能转换成小数", v)
+	},
+	"to_bool": func(v interface{}, _ string) (interface{}, error) {
+		switch _v := v.(type) {
+		case string:
+			return strconv.ParseBool(strings.TrimSpace(_v))
+		case float64:
+			return _v != 0, nil
+		case int64:
+			return _v != 0, nil
+		case bool:
+			return _v, nil
+		}
+		return nil, fmt.Errorf("类型%T不能转换成bool", v)
+	},
+	"to_time": func(v interface{}, layout string) (interface{}, error) {
+		switch _v := v.(type) {
+		case string:
+			return time.Parse(layout, strings.TrimSpace(_v))
+		case float64:
+			return time.Unix(int64(_v), 0), nil
+		case int64:
+			return time.Unix(_v, 0), nil
+		case time.Time:
+			return _v, nil
+		}
+		return nil, fmt.Errorf("类型%T不能转换成时间", v)
+	},
+}
+
+func kFormString(v interface{}, fn func(string) string) (interface{}, error) {
+	_s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("类型%T不是字符串", v)
+	}
+	return fn(_s), nil
+}
+
+// transform 执行字段声明的默认值和转换, 默认值只在字段不存在或者为null并且上一级存在时设置
+func (t *KForm) transform(v kFormValue) (kFormValue, *ValidationError) {
+	if (!v.ok || v.value == nil) && t.hasDefault && v.set != nil {
+		v.value, v.ok = copyKFormDoc(t.Default), true
+		v.set(v.value)
+	}
+
+	if !v.ok || v.value == nil {
+		return v, nil
+	}
+
+	for _, _name := range t.Transform {
+		_nv, err := kFormTransforms[_name](v.value, t.Layout)
+		if err != nil {
+			return v, &ValidationError{Field: v.path, Rule: _name, Code: t.Code,
+				Message: fmt.Sprintf("%s的值[%v]转换(%s)失败: %s", v.path, v.value, _name, err)}
+		}
+		v.value = _nv
+	}
+
+	if v.set != nil {
+		v.set(v.value)
+	}
+	return v, nil
+}
+
+// copyKFormDoc 复制json文档, 转换只修改副本
+func copyKFormDoc(v interface{}) interface{} {
+	switch _v := v.(type) {
+	case map[string]interface{}:
+		_m := make(map[string]interface{}, len(_v))
+		for k, e := range _v {
+			_m[k] = copyKFormDoc(e)
+		}
+		return _m
+	case []interface{}:
+		_a := make([]interface{}, len(_v))
+		for i, e := range _v {
+			_a[i] = copyKFormDoc(e)
+		}
+		return _a
+	}
+	return v
+}
@@ -0,0 +1,489 @@
+package kweb
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/kooksee/kweb/internal/validator"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// kJSCompare 可以转换成客户端代码的比较函数, 值为TypeScript中对应的函数
+var kJSCompare = map[string]string{
+	"Gte": "Gte", "Gt": "Gt", "Lte": "Lte", "Lt": "Lt", "Min": "Gte", "Max": "Lte", "Eq": "Eq", "Ne": "Ne",
+	"IsGte": "Gte", "IsGt": "Gt", "IsLte": "Lte", "IsLt": "Lt", "IsEq": "Eq", "IsNe": "Ne",
+	"HasMinOf": "Gte", "HasMaxOf": "Lte", "HasLengthOf": "Eq",
+	"Contains": "Contains", "Excludes": "Excludes", "ContainsAny": "ContainsAny",
+}
+
+// kJSTransforms 客户端支持的转换, to_time依赖Go的layout, 只在服务端执行
+var kJSTransforms = map[string]bool{"trim": true, "lower": true, "upper": true, "to_int": true, "to_float": true, "to_bool": true}
+
+var kGoRegexHex = regexp.MustCompile(`\\x\{([0-9A-Fa-f]+)\}`)
+
+// jsRegex 把Go的正则表达式转换成JavaScript的正则表达式和flags, 不支持的语法返回false
+func jsRegex(re string) (string, string, bool) {
+	_flags := "u"
+	if strings.HasPrefix(re, "(?i)") {
+		re, _flags = re[4:], "iu"
+	}
+
+	re = strings.Replace(re, "(?P<", "(?<", -1)
+	for i := strings.Index(re, "(?"); i >= 0; i = strings.Index(re, "(?") {
+		if i+2 >= len(re) || re[i+2] != ':' && re[i+2] != '<' {
+			return "", "", false
+		}
+		re = re[:i] + "(\x00" + re[i+2:]
+	}
+	re = strings.Replace(re, "(\x00", "(?", -1)
+
+	if strings.Contains(re, "[:") || strings.Contains(re, `\Q`) || strings.Contains(re, `\z`) || strings.Contains(re, `\A`) {
+		return "", "", false
+	}
+	return kGoRegexHex.ReplaceAllString(re, `\u{$1}`), _flags, true
+}
+
+// jsKFormCall 把一次调用转换成TypeScript表达式, 值为v
+func jsKFormCall(c *kRuleCall, patterns map[string]string) (string, bool) {
+	_args := []string{"v"}
+	for _, _a := range c.Args {
+		_dt, err := jsJSON(_a)
+		if err != nil {
+			return "", false
+		}
+		_args = append(_args, string(_dt))
+	}
+
+	switch {
+	case kJSCompare[c.Name] != "" && len(c.Args) == 1:
+		return kJSCompare[c.Name] + "(" + strings.Join(_args, ", ") + ")", true
+	case c.Name == "Len" && (len(c.Args) == 1 || len(c.Args) == 2):
+		return "Len(" + strings.Join(_args, ", ") + ")", true
+	case c.Name == "OneOf" || c.Name == "IsType":
+		return c.Name + "(" + strings.Join(_args, ", ") + ")", true
+	case c.Name == "Match" && len(c.Args) == 1:
+		_p, ok := c.Args[0].(string)
+		if !ok {
+			return "", false
+		}
+
+		_re, _flags, ok := jsRegex(_p)
+		if !ok {
+			return "", false
+		}
+		_dt, _ := jsJSON(_re)
+		return fmt.Sprintf("Match(v, new RegExp(%s, %q))", _dt, _flags), true
+	case validator.Patterns[c.Name] != "" && len(c.Args) == 0:
+		_re, _flags, ok := jsRegex(validator.Patterns[c.Name])
+		if !ok {
+			return "", false
+		}
+		_dt, _ := jsJSON(_re)
+		patterns[c.Name] = fmt.Sprintf("new RegExp(%s, %q)", _dt, _flags)
+		return fmt.Sprintf("Test(v, P.%s, %t)", c.Name, c.Name == "IsNumber" || c.Name == "IsNumeric"), true
+	}
+	return "", false
+}
+
+// GenFormsTS 把表单规则转换成独立的TypeScript校验模块, 信息和服务端相同, form为空时转换全部表单
+// 文档规则, to_time以及数据库查询等不能转换的规则只在服务端执行, 作为问题返回
+func GenFormsTS(forms *KForms, form string) ([]byte, []*KFormsProblem, error) {
+	_names := forms.Forms()
+	if form != "" {
+		if _, ok := forms.data[form]; !ok {
+			return nil, nil, fmt.Errorf("表单[%s]不存在", form)
+		}
+		_names = []string{form}
+	}
+
+	var _ps []*KFormsProblem
+	var _body bytes.Buffer
+	_patterns := make(map[string]string)
+	for _, _name := range _names {
+		fmt.Fprintf(&_body, "  %s: [\n", jsString(_name))
+		for _, _r := range forms.data[_name] {
+			_problem := func(format string, args ...interface{}) {
+				_ps = append(_ps, &KFormsProblem{File: _r.File, Line: _r.Line, Form: _name, Field: _r.Field, Msg: fmt.Sprintf(format, args...)})
+			}
+
+			_entry := map[string]interface{}{"field": _r.Field}
+			for k, v := range map[string]string{"rule": _r.Rule, "msg": _r.Msg, "code": _r.Code} {
+				if v != "" {
+					_entry[k] = v
+				}
+			}
+
+			switch {
+			case _r.isDoc():
+				_problem("文档规则[%s]只在服务端执行", _r.Rule)
+				continue
+			case _r.isDecl():
+				_decl := make(map[string]interface{})
+				for k, v := range map[string]bool{"required": _r.Required, "nullable": _r.Nullable, "optional": _r.Optional} {
+					if v {
+						_decl[k] = true
+					}
+				}
+
+				var _ts []string
+				for _, _t := range _r.Transform {
+					if kJSTransforms[_t] {
+						_ts = append(_ts, _t)
+					} else {
+						_problem("转换[%s]只在服务端执行", _t)
+					}
+				}
+				if len(_ts) > 0 {
+					_decl["transform"] = _ts
+				}
+				if _r.hasDefault {
+					_decl["default"] = _r.Default
+				}
+				_entry["decl"] = _decl
+			}
+
+			_dt, err := jsJSON(_entry)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if _r.isDecl() {
+				fmt.Fprintf(&_body, "    %s,\n", _dt)
+				continue
+			}
+
+			_cs, ok := parseKRuleCalls(_r.Rule)
+			var _js []string
+			for _, _c := range _cs {
+				_j, _ok := jsKFormCall(_c, _patterns)
+				ok = ok && _ok
+				_js = append(_js, _j)
+			}
+			if !ok {
+				_problem("规则[%s]只在服务端执行", _r.Rule)
+				continue
+			}
+
+			fmt.Fprintf(&_body, "    %s, check: (v: Value) => %s},\n", _dt[:len(_dt)-1], strings.Join(_js, " && "))
+		}
+		_body.WriteString("  ],\n")
+	}
+
+	var _b bytes.Buffer
+	_b.WriteString("// Code generated by kweb form gen-js. DO NOT EDIT.\n")
+	_b.WriteString(kFormsTSRuntime)
+
+	_keys := make([]string, 0, len(_patterns))
+	for k := range _patterns {
+		_keys = append(_keys, k)
+	}
+	sort.Strings(_keys)
+
+	_b.WriteString("\nconst P: Record<string, RegExp> = {\n")
+	for _, k := range _keys {
+		fmt.Fprintf(&_b, "  %s: %s,\n", k, _patterns[k])
+	}
+	_b.WriteString("};\n\nexport const forms: Record<string, Rule[]> = {\n")
+	_b.Write(_body.Bytes())
+	_b.WriteString("};\n")
+	return _b.Bytes(), _ps, nil
+}
+
+// jsJSON 输出json, 不转义&<>, 生成的代码更容易阅读
+func jsJSON(v interface{}) ([]byte, error) {
+	var _b bytes.Buffer
+	_enc := json.NewEncoder(&_b)
+	_enc.SetEscapeHTML(false)
+	if err := _enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSpace(_b.Bytes()), nil
+}
+
+func jsString(s string) string {
+	_dt, _ := jsJSON(s)
+	return string(_dt)
+}
+
+// kFormsTSRuntime 客户端校验的运行时, 语义和internal/validator以及KForms.Validate一致
+const kFormsTSRuntime = `
+/* eslint-disable */
+type Value = any;
+
+export interface ValidationError {
+  field: string;
+  rule: string;
+  code?: string;
+  message: string;
+}
+
+export interface ValidationResult {
+  form: string;
+  ok: boolean;
+  errors: ValidationError[];
+  fields: Record<string, string[]>;
+  doc: Record<string, Value>;
+}
+
+interface Decl {
+  required?: boolean;
+  nullable?: boolean;
+  optional?: boolean;
+  transform?: string[];
+  default?: Value;
+}
+
+interface Rule {
+  field: string;
+  rule?: string;
+  msg?: string;
+  code?: string;
+  decl?: Decl;
+  check?: (v: Value) => boolean;
+}
+
+interface Slot {
+  path: string;
+  value: Value;
+  ok: boolean;
+  set?: (v: Value) => void;
+}
+
+const isObject = (v: Value): boolean => v !== null && typeof v === "object" && !Array.isArray(v);
+
+// lastErr 和服务端校验函数的错误一致, 规则的信息中没有{err}时附加在信息后面
+let lastErr = "";
+
+function fail(msg: string): boolean {
+  lastErr = msg;
+  return false;
+}
+
+// kind json值在服务端的类型名
+function kind(v: Value): string {
+  if (v === null || v === undefined) return "invalid";
+  if (typeof v === "number") return "float64";
+  if (typeof v === "boolean") return "bool";
+  if (Array.isArray(v)) return "slice";
+  return isObject(v) ? "map" : typeof v;
+}
+
+function size(v: Value): number | undefined {
+  if (typeof v === "string") return Array.from(v).length;
+  if (Array.isArray(v)) return v.length;
+  if (isObject(v)) return Object.keys(v).length;
+  return undefined;
+}
+
+function num(v: Value): number | undefined {
+  if (typeof v === "number") return v;
+  if (typeof v === "string" && v.trim() !== "" && !isNaN(Number(v))) return Number(v);
+  return undefined;
+}
+
+function cmp(v: Value, p: Value): number | undefined {
+  if (typeof v === "string" && typeof p === "string") return v < p ? -1 : v > p ? 1 : 0;
+  const n = num(p);
+  if (n === undefined) {
+    fail("参数[" + String(p) + "]不是数字");
+    return undefined;
+  }
+  const s = size(v) !== undefined ? size(v) : typeof v === "number" ? v : undefined;
+  if (s === undefined) {
+    fail("字段类型[" + kind(v) + "]不能比较大小");
+    return undefined;
+  }
+  return Math.sign(s - n);
+}
+
+function equal(v: Value, p: Value): boolean {
+  if (typeof v === "string" || typeof v === "boolean") return v === p;
+  if (v === null || v === undefined) return p === null;
+  if (typeof p === "string") return false;
+  const a = num(v);
+  const b = num(p);
+  return a !== undefined && b !== undefined && a === b;
+}
+
+const Gte = (v: Value, p: Value) => { const c = cmp(v, p); return c !== undefined && c >= 0; };
+const Gt = (v: Value, p: Value) => { const c = cmp(v, p); return c !== undefined && c > 0; };
+const Lte = (v: Value, p: Value) => { const c = cmp(v, p); return c !== undefined && c <= 0; };
+const Lt = (v: Value, p: Value) => { const c = cmp(v, p); return c !== undefined && c < 0; };
+const Eq = (v: Value, p: Value) => typeof p === "boolean" ? v === p : cmp(v, p) === 0;
+const Ne = (v: Value, p: Value) => {
+  if (typeof p === "boolean") return typeof v === "boolean" && v !== p;
+  const c = cmp(v, p);
+  return c !== undefined && c !== 0;
+};
+
+function Len(v: Value, min: number, max?: number): boolean {
+  const l = size(v);
+  if (l === undefined) return fail("字段类型[" + kind(v) + "]没有长度");
+  return max === undefined ? l === min : l >= min && l <= max;
+}
+
+const OneOf = (v: Value, ...ps: Value[]) => ps.some((p) => equal(v, p));
+
+const jsonTypes: Record<string, (v: Value) => boolean> = {
+  null: (v) => v === null || v === undefined,
+  string: (v) => typeof v === "string",
+  number: (v) => typeof v === "number",
+  integer: (v) => Number.isInteger(v),
+  boolean: (v) => typeof v === "boolean",
+  array: (v) => Array.isArray(v),
+  object: (v) => isObject(v),
+};
+const IsType = (v: Value, ...types: string[]) =>
+  types.some((t) => jsonTypes[t] !== undefined && jsonTypes[t](v)) || fail("字段类型[" + kind(v) + "]不是[" + types.join(" ") + "]");
+
+function Contains(v: Value, p: Value): boolean {
+  if (typeof v === "string") return typeof p === "string" && v.includes(p);
+  if (Array.isArray(v)) return v.some((e) => equal(e, p));
+  if (isObject(v)) return typeof p === "string" && Object.prototype.hasOwnProperty.call(v, p);
+  return fail("字段类型[" + kind(v) + "]不支持Contains");
+}
+
+const Excludes = (v: Value, p: Value) =>
+  typeof v === "string" || Array.isArray(v) || isObject(v) ? !Contains(v, p) : fail("字段类型[" + kind(v) + "]不支持Excludes");
+const ContainsAny = (v: Value, chars: string) =>
+  typeof v === "string" ? Array.from(chars).some((c) => v.includes(c)) : fail("字段类型[" + kind(v) + "]不是字符串");
+const Match = (v: Value, re: RegExp) =>
+  typeof v === "number" || typeof v === "string" ? re.test(String(v)) : fail("字段类型[" + kind(v) + "]不支持Match");
+const Test = (v: Value, re: RegExp, numbers: boolean) => (numbers && typeof v === "number") || (typeof v === "string" && re.test(v));
+
+const transforms: Record<string, (v: Value) => Value> = {
+  trim: (v) => { if (typeof v !== "string") throw new Error("不是字符串"); return v.trim(); },
+  lower: (v) => { if (typeof v !== "string") throw new Error("不是字符串"); return v.toLowerCase(); },
+  upper: (v) => { if (typeof v !== "string") throw new Error("不是字符串"); return v.toUpperCase(); },
+  to_int: (v) => {
+    if (typeof v === "string" && /^[+-]?\d+$/.test(v.trim())) return parseInt(v.trim(), 10);
+    if (typeof v === "number" && Number.isInteger(v)) return v;
+    throw new Error("不能转换成整数");
+  },
+  to_float: (v) => {
+    const n = num(v);
+    if (n === undefined) throw new Error("不能转换成小数");
+    return n;
+  },
+  to_bool: (v) => {
+    if (typeof v === "boolean") return v;
+    if (typeof v === "number") return v !== 0;
+    if (typeof v === "string") {
+      if (["1", "t", "T", "true", "TRUE", "True"].includes(v.trim())) return true;
+      if (["0", "f", "F", "false", "FALSE", "False"].includes(v.trim())) return false;
+    }
+    throw new Error("不能转换成bool");
+  },
+};
+
+function resolve(doc: Value, field: string): Slot[] {
+  let slots: Slot[] = [{ path: "", value: doc, ok: true }];
+  for (const seg of field.match(/[^.[\]]+|\[[^\]]*\]/g) || []) {
+    const next: Slot[] = [];
+    for (const s of slots) {
+      if (seg === "[]" || seg === "[*]") {
+        if (Array.isArray(s.value)) {
+          const a = s.value;
+          a.forEach((e: Value, i: number) => next.push({ path: s.path + "[" + i + "]", value: e, ok: true, set: (v) => { a[i] = v; } }));
+        }
+      } else if (seg[0] === "[") {
+        const a = s.value;
+        const i = Number(seg.slice(1, -1));
+        const ok = s.ok && Array.isArray(a) && i < a.length;
+        next.push({ path: s.path + seg, value: ok ? a[i] : undefined, ok, set: ok ? (v) => { a[i] = v; } : undefined });
+      } else {
+        const m = s.value;
+        const obj = s.ok && isObject(m);
+        const ok = obj && Object.prototype.hasOwnProperty.call(m, seg);
+        next.push({ path: s.path ? s.path + "." + seg : seg, value: ok ? m[seg] : undefined, ok, set: obj ? (v) => { m[seg] = v; } : undefined });
+      }
+    }
+    slots = next;
+  }
+  return slots;
+}
+
+// render 和服务端KForm.failure一致, 信息中没有{err}时把错误附加在后面
+function render(msg: string, field: string, value: Value, err = ""): string {
+  const s = msg.replace(/\{(field|value|err)\}/g, (_, k) => k === "field" ? field : k === "value" ? String(value) : err);
+  return err !== "" && !/\{err\}/.test(msg) ? s + ", Err:" + err : s;
+}
+
+function missing(d: Decl, s: Slot): boolean {
+  return !!d.required && (!s.ok || (s.value === null && !d.nullable) || s.value === "");
+}
+
+function skips(d: Decl | undefined, s: Slot): boolean {
+  if (!s.ok) return true;
+  if (!d) return false;
+  if (s.value === null) return !!(d.nullable || d.required);
+  if (s.value === "") return !!(d.optional || d.required);
+  return false;
+}
+
+// validate 和服务端KForms.Validate的语义一致, 只执行可以在客户端执行的规则
+export function validate(form: string, input: Record<string, Value>, opts: { firstPerField?: boolean } = {}): ValidationResult {
+  const rules = forms[form];
+  if (!rules) throw new Error("表单[" + form + "]不存在");
+
+  const doc = JSON.parse(JSON.stringify(input || {}));
+  const res: ValidationResult = { form, ok: true, errors: [], fields: {}, doc };
+  const add = (r: Rule, field: string, message: string, rule?: string) => {
+    res.errors.push({ field, rule: rule || r.rule || "", code: r.code, message });
+    (res.fields[field] = res.fields[field] || []).push(message);
+  };
+
+  const failed: Record<string, boolean> = {};
+  const broken: Record<string, boolean> = {};
+  const decls: Record<string, Decl> = {};
+  for (const r of rules) {
+    if (!r.decl) continue;
+    decls[r.field] = r.decl;
+    for (const s of resolve(doc, r.field)) {
+      if ((!s.ok || s.value === null) && r.decl.default !== undefined && s.set) {
+        s.value = JSON.parse(JSON.stringify(r.decl.default));
+        s.ok = true;
+        s.set(s.value);
+      }
+      if (!s.ok || s.value === null) continue;
+
+      for (const t of r.decl.transform || []) {
+        try {
+          s.value = transforms[t](s.value);
+        } catch (e) {
+          broken[s.path] = failed[s.path] = true;
+          add(r, s.path, s.path + "的值[" + String(s.value) + "]转换(" + t + ")失败: " + (e as Error).message, t);
+          break;
+        }
+      }
+      if (!broken[s.path] && s.set) s.set(s.value);
+    }
+  }
+
+  for (const r of rules) {
+    for (const s of resolve(doc, r.field)) {
+      if (broken[s.path]) continue;
+
+      if (r.decl) {
+        if (missing(r.decl, s)) {
+          failed[s.path] = true;
+          add(r, s.path, render(r.msg || "{field}是必填字段", s.path, s.value));
+        }
+        continue;
+      }
+
+      if (skips(decls[r.field], s) || (opts.firstPerField && failed[s.path])) continue;
+      lastErr = "";
+      if (!r.check!(s.value)) {
+        failed[s.path] = true;
+        add(r, s.path, render(r.msg || "", s.path, s.value, lastErr));
+      }
+    }
+  }
+
+  res.ok = res.errors.length === 0;
+  return res;
+}
+`
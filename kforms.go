@@ -5,62 +5,672 @@ import (
 	"github.com/BurntSushi/toml"
 	"github.com/antonmedv/expr"
 	"github.com/kooksee/kweb/internal/g"
+	"github.com/kooksee/kweb/internal/kmsg"
+	"github.com/kooksee/kweb/internal/ktoml"
 	"github.com/kooksee/kweb/internal/validator"
 	"io"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// kFormParams 表单信息中可以使用的占位符
+var kFormParams = []string{"field", "value", "err"}
+
+const (
+	kFormPhaseBefore = "before"
+	kFormPhaseAfter  = "after"
+
+	// kFormRequiredMsg 没有配置msg时字段缺失的信息
+	kFormRequiredMsg = "{field}是必填字段"
+)
+
+// RegisterValidator 注册字段规则中可以使用的校验函数, 需要在加载表单规则之前注册
+// fn的参数依次为可选的context.Context, 字段的值和规则中的参数, 返回bool, (bool, string)或者error
+// 返回的string或者error作为{err}和校验失败的信息, context来自WithContext
+//
+//	kweb.RegisterValidator("IsOrderNo", func(v string) bool { return strings.HasPrefix(v, "NO") })
+//	no = ["IsOrderNo()", "订单号格式错误"]
+func RegisterValidator(name string, fn interface{}) {
+	g.AssertErr(validator.Register(name, fn), "注册校验函数失败")
+}
+
+// KForm 表单字段的一条规则
+//
+// 表单是toml中的一个表, 表名就是表单名, 例如[user.create]; 表中的每个键是一个字段, 值是规则列表, 按照书写的顺序执行
+//
+//	name = ["IsAlpha()", "信息"]                                  一条规则, 可以带第三个元素code
+//	name = [["Required()", "必填"], ["IsAlpha()", "只能是字母"]]  多条规则
+//	name = [{rule = "IsAlpha()", msg = "只能是字母", code = "10001"}]
+//
+// 字段可以是带引号的路径, 校验嵌套的对象和数组的每个元素, 失败时报告具体的路径, 例如items[3].qty
+//
+//	"address.city" = ["IsAlpha()", "城市只能是字母"]
+//	"items[*].qty" = ["Gte(1)", "数量至少为1"]
+//	"tags[]" = ["Len(1, 16)", "标签长度错误"]
+//
+// 没有rule的表格是字段声明, 每个字段最多一个, msg为字段缺失时的信息
+//
+//	name = [{required = true, transform = ["trim"], msg = "姓名必填"}, {rule = "IsAlpha()", msg = "只能是字母"}]
+//	age = [{transform = "to_int", default = 18}, {rule = "Gte(18)", msg = "未成年"}]
+//	birthday = [{transform = "to_time", layout = "2006-01-02"}]
+//
+// 默认值和转换在所有规则之前执行, 转换的列表见kFormTransforms, 校验的结果中包含转换之后的文档
+//
+//	字段不存在: required时报错, 否则跳过字段的规则
+//	字段为null: nullable时跳过字段的规则, required时报错, 否则执行规则
+//	字段为"":   required时报错, optional时跳过字段的规则, 否则执行规则
+//
+// __开头的键是针对整个文档的规则, 默认在字段规则之后执行, phase = "before" 时在字段规则之前执行
 type KForm struct {
-	Parser expr.Node
-	Msg    string
+	Field     string
+	Rule      string
+	Code      string
+	Phase     string
+	Parser    expr.Node
+	Msg       string
+	Required  bool
+	Nullable  bool
+	Optional  bool
+	Transform []string
+	Layout    string
+	Default   interface{}
+	File      string
+	Line      int
+
+	msg        *kmsg.Msg
+	path       kFormPath
+	hasDefault bool
+}
+
+func (t *KForm) isDoc() bool {
+	return strings.HasPrefix(t.Field, "__")
+}
+
+// isDecl 是否是字段声明
+func (t *KForm) isDecl() bool {
+	return t.Rule == "" && !t.isDoc()
+}
+
+// missing 字段的值不满足required
+func (t *KForm) missing(v kFormValue) bool {
+	if !t.Required {
+		return false
+	}
+	return !v.ok || v.value == nil && !t.Nullable || v.value == ""
+}
+
+// skips 字段的值是否跳过字段的规则, t为nil时只跳过不存在的字段
+func (t *KForm) skips(v kFormValue) bool {
+	switch {
+	case !v.ok:
+		return true
+	case t == nil:
+		return false
+	case v.value == nil:
+		return t.Nullable || t.Required
+	case v.value == "":
+		return t.Optional || t.Required
+	}
+	return false
 }
 
 type KForms struct {
-	data map[string]map[string]*KForm
+	data  map[string][]*KForm
+	forms []string
+
+	// shadows 被后加载的文件覆盖的字段, 由Check报告
+	shadows []*KFormsProblem
+}
+
+// KFormsProblem 加载或者检查表单文件时发现的问题
+type KFormsProblem struct {
+	File  string
+	Line  int
+	Form  string
+	Field string
+	Msg   string
+}
+
+func (t *KFormsProblem) Error() string {
+	_s := t.File
+	if t.Line > 0 {
+		_s += ":" + strconv.Itoa(t.Line)
+	}
+	if t.Form != "" {
+		_s += ": " + t.Form
+		if t.Field != "" {
+			_s += "." + t.Field
+		}
+	}
+	return _s + ": " + t.Msg
 }
 
 func (t *KForms) FromPath(cfg string) {
-	var _dt map[string]map[string][]string
+	_src, err := ioutil.ReadFile(cfg)
+	g.AssertErr(err, "文件[%s]读取失败", cfg)
+
+	var _ms []string
+	for _, _p := range t.loadFile(cfg, _src) {
+		_ms = append(_ms, _p.Error())
+	}
+	g.AssertBool(len(_ms) > 0, "文件[%s]解析失败: %s", cfg, strings.Join(_ms, "; "))
+}
+
+// FromDir 加载目录下所有的toml文件, 返回全部问题
+func (t *KForms) FromDir(dir string) []*KFormsProblem {
+	_files, err := filepath.Glob(filepath.Join(dir, "*.toml"))
+	if err != nil {
+		return []*KFormsProblem{{File: dir, Msg: fmt.Sprintf("文件匹配失败: %s", err)}}
+	}
+
+	var _ps []*KFormsProblem
+	for _, _file := range _files {
+		_src, err := ioutil.ReadFile(_file)
+		if err != nil {
+			_ps = append(_ps, &KFormsProblem{File: _file, Msg: fmt.Sprintf("文件读取失败: %s", err)})
+			continue
+		}
+		_ps = append(_ps, t.loadFile(_file, _src)...)
+	}
+	return _ps
+}
+
+// FromFS 加载fsys中所有匹配pattern的toml文件, 按照文件名的顺序, 后加载的文件覆盖同名的字段
+func (t *KForms) FromFS(fsys fs.FS, pattern string) []*KFormsProblem {
+	_names, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return []*KFormsProblem{{File: pattern, Msg: fmt.Sprintf("文件匹配失败: %s", err)}}
+	}
 
-	_, err := toml.DecodeFile(cfg, &_dt)
-	g.AssertErr(err, "文件[%s]解析失败", cfg)
+	var _ps []*KFormsProblem
+	for _, _name := range _names {
+		_src, err := fs.ReadFile(fsys, _name)
+		if err != nil {
+			_ps = append(_ps, &KFormsProblem{File: _name, Msg: fmt.Sprintf("文件读取失败: %s", err)})
+			continue
+		}
+		_ps = append(_ps, t.loadFile(_name, _src)...)
+	}
+	return _ps
+}
+
+// Forms 已经加载的表单, 按照声明的顺序
+func (t *KForms) Forms() []string {
+	return t.forms
+}
+
+// Rules 表单的全部规则, 按照声明的顺序
+func (t *KForms) Rules(form string) []*KForm {
+	return t.data[form]
+}
+
+// Check 检查已经加载的表单: 重复的规则, 不起作用的声明以及被后加载的文件覆盖的字段
+// 规则的语法, 未知的函数, 参数的类型和值, 结果的类型在加载时检查
+func (t *KForms) Check() []*KFormsProblem {
+	_ps := append([]*KFormsProblem{}, t.shadows...)
+
+	for _, _form := range t.forms {
+		_counts := make(map[string]int)
+		for _, _r := range t.data[_form] {
+			if !_r.isDecl() {
+				_counts[_r.Field]++
+			}
+		}
 
-	for k := range _dt {
-		for j := range _dt[k] {
-			var _fn []expr.OptionFn
+		_seen := make(map[string]bool)
+		for _, _r := range t.data[_form] {
+			_problem := func(format string, args ...interface{}) {
+				_ps = append(_ps, &KFormsProblem{File: _r.File, Line: _r.Line, Form: _form, Field: _r.Field, Msg: fmt.Sprintf(format, args...)})
+			}
 
-			if !strings.HasPrefix(j, "__") {
-				_fn = append(_fn, expr.Env(&validator.KValidator{}))
+			if _r.isDecl() {
+				if _counts[_r.Field] == 0 && !_r.Required && len(_r.Transform) == 0 && !_r.hasDefault {
+					_problem("字段没有规则, nullable和optional不起作用")
+				}
+				continue
 			}
 
-			p, err := expr.Parse(_dt[k][j][0], _fn...)
-			g.AssertErr(err, "规则[%s]解析失败", _dt[k][j][0])
-			t.data[k][j] = &KForm{Parser: p, Msg: _dt[k][j][1]}
+			_k := _r.Field + "\x00" + _r.Rule
+			if _seen[_k] {
+				_problem("规则[%s]重复", _r.Rule)
+				continue
+			}
+			_seen[_k] = true
 		}
 	}
+	return _ps
+}
+
+func (t *KForms) loadFile(file string, src []byte) []*KFormsProblem {
+	var _dt map[string]interface{}
+
+	_md, err := toml.Decode(string(src), &_dt)
+	if err != nil {
+		return []*KFormsProblem{{File: file, Msg: fmt.Sprintf("文件解析失败: %s", err)}}
+	}
+
+	if t.data == nil {
+		t.data = make(map[string][]*KForm)
+	}
+
+	var _ps []*KFormsProblem
+	_lines := ktoml.ParseLines(src)
+
+	// toml解析成map之后就丢失了顺序, 按照MetaData.Keys的顺序(即书写的顺序)加载规则
+	for _, _key := range _md.Keys() {
+		if len(_key) < 2 {
+			continue
+		}
+
+		_v, ok := tomlLookup(_dt, _key)
+		if !ok {
+			continue
+		}
+
+		_entries, ok := _v.([]interface{})
+		if !ok {
+			continue
+		}
+
+		_form := strings.Join(_key[:len(_key)-1], ".")
+		_field := _key[len(_key)-1]
+		_problem := func(format string, args ...interface{}) {
+			_ps = append(_ps, &KFormsProblem{File: file, Line: _lines.Line(_key...), Form: _form, Field: _field, Msg: fmt.Sprintf(format, args...)})
+		}
+
+		_specs, err := parseKFormEntries(_entries)
+		if err != "" {
+			_problem("%s", err)
+			continue
+		}
+
+		_decls := 0
+		for _, _s := range _specs {
+			if _s.isDecl() {
+				_decls++
+			}
+		}
+		if _decls > 1 {
+			_problem("字段只能有一个声明, 现在有%d个", _decls)
+			continue
+		}
+
+		if _, ok := t.data[_form]; !ok {
+			t.forms = append(t.forms, _form)
+		}
+
+		// 后加载的文件中同名的字段覆盖之前的规则
+		for _, _r := range t.data[_form] {
+			if _r.Field == _field {
+				t.shadows = append(t.shadows, &KFormsProblem{File: file, Line: _lines.Line(_key...), Form: _form, Field: _field,
+					Msg: fmt.Sprintf("覆盖了%s:%d中的规则", _r.File, _r.Line)})
+				break
+			}
+		}
+		t.data[_form] = dropKFormField(t.data[_form], _field)
+
+		for _, _s := range _specs {
+			_f, err := compileKForm(_field, _s)
+			if err != "" {
+				_problem("%s", err)
+				continue
+			}
+			_f.File, _f.Line = file, _lines.Line(_key...)
+			t.data[_form] = append(t.data[_form], _f)
+		}
+	}
+
+	return _ps
+}
+
+func dropKFormField(rules []*KForm, field string) []*KForm {
+	var _rs []*KForm
+	for _, _r := range rules {
+		if _r.Field != field {
+			_rs = append(_rs, _r)
+		}
+	}
+	return _rs
+}
+
+// tomlLookup 按照路径在解析后的toml中取值
+func tomlLookup(dt map[string]interface{}, key []string) (interface{}, bool) {
+	var _v interface{} = dt
+	for _, k := range key {
+		_m, ok := _v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		if _v, ok = _m[k]; !ok {
+			return nil, false
+		}
+	}
+	return _v, true
+}
+
+// kFormSpec 一条规则的定义
+type kFormSpec struct {
+	Rule       string
+	Msg        string
+	Code       string
+	Phase      string
+	Required   bool
+	Nullable   bool
+	Optional   bool
+	Transform  []string
+	Layout     string
+	Default    interface{}
+	hasDefault bool
+}
+
+func (t *kFormSpec) isDecl() bool {
+	return t.Rule == "" && (t.Required || t.Nullable || t.Optional || len(t.Transform) > 0 || t.Layout != "" || t.hasDefault)
 }
 
+// parseKFormEntries 解析一个字段的规则列表, 支持单条规则, 多条规则以及表格的形式
+func parseKFormEntries(entries []interface{}) ([]*kFormSpec, string) {
+	if len(entries) == 0 {
+		return nil, "规则列表不能为空"
+	}
+
+	if _, ok := entries[0].(string); ok {
+		_s, err := parseKFormArray(entries)
+		if err != "" {
+			return nil, err
+		}
+		return []*kFormSpec{_s}, ""
+	}
+
+	var _ss []*kFormSpec
+	for i, _e := range entries {
+		var _s *kFormSpec
+		var err string
+
+		switch _v := _e.(type) {
+		case []interface{}:
+			_s, err = parseKFormArray(_v)
+		case map[string]interface{}:
+			_s, err = parseKFormTable(_v)
+		default:
+			err = fmt.Sprintf("类型错误: %T", _e)
+		}
+
+		if err != "" {
+			return nil, fmt.Sprintf("第%d条规则%s", i+1, err)
+		}
+		_ss = append(_ss, _s)
+	}
+	return _ss, ""
+}
+
+func parseKFormArray(d []interface{}) (*kFormSpec, string) {
+	if len(d) < 2 || len(d) > 3 {
+		return nil, "格式错误, 应该是[\"规则\", \"信息\"]或者[\"规则\", \"信息\", \"code\"]"
+	}
+
+	_ss := make([]string, len(d))
+	for i := range d {
+		_v, ok := d[i].(string)
+		if !ok {
+			return nil, fmt.Sprintf("第%d个元素类型错误: %T", i+1, d[i])
+		}
+		_ss[i] = _v
+	}
+
+	_s := &kFormSpec{Rule: _ss[0], Msg: _ss[1]}
+	if len(_ss) > 2 {
+		_s.Code = _ss[2]
+	}
+	return _s, ""
+}
+
+func parseKFormTable(d map[string]interface{}) (*kFormSpec, string) {
+	_s := &kFormSpec{}
+	_strs := map[string]*string{"rule": &_s.Rule, "msg": &_s.Msg, "code": &_s.Code, "phase": &_s.Phase, "layout": &_s.Layout}
+	_bools := map[string]*bool{"required": &_s.Required, "nullable": &_s.Nullable, "optional": &_s.Optional}
+	for _, k := range sortedKeys(d) {
+		_ok := false
+		if _p, ok := _strs[k]; ok {
+			*_p, _ok = d[k].(string)
+		} else if _p, ok := _bools[k]; ok {
+			*_p, _ok = d[k].(bool)
+		} else if k == "default" {
+			_s.Default, _s.hasDefault, _ok = d[k], true, true
+		} else if k == "transform" {
+			_s.Transform, _ok = kFormStrings(d[k])
+		} else {
+			return nil, fmt.Sprintf("未知的字段[%s]", k)
+		}
+
+		if !_ok {
+			return nil, fmt.Sprintf("[%s]类型错误: %T", k, d[k])
+		}
+	}
+	return _s, ""
+}
+
+func sortedKeys(m interface{}) []string {
+	var _ks []string
+	for _, _k := range reflect.ValueOf(m).MapKeys() {
+		_ks = append(_ks, _k.String())
+	}
+	sort.Strings(_ks)
+	return _ks
+}
+
+func containsString(ss []string, s string) bool {
+	for _, _s := range ss {
+		if _s == s {
+			return true
+		}
+	}
+	return false
+}
+
+// kFormStrings 字符串或者字符串数组
+func kFormStrings(v interface{}) ([]string, bool) {
+	if _s, ok := v.(string); ok {
+		return []string{_s}, true
+	}
+
+	_a, ok := v.([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	_ss := make([]string, len(_a))
+	for i := range _a {
+		if _ss[i], ok = _a[i].(string); !ok {
+			return nil, false
+		}
+	}
+	return _ss, true
+}
+
+func compileKForm(field string, s *kFormSpec) (*KForm, string) {
+	_f := &KForm{Field: field, Rule: s.Rule, Code: s.Code, Phase: s.Phase, Msg: s.Msg, msg: kmsg.Parse(s.Msg),
+		Required: s.Required, Nullable: s.Nullable, Optional: s.Optional,
+		Transform: s.Transform, Layout: s.Layout, Default: s.Default, hasDefault: s.hasDefault}
+
+	_decl := s.Required || s.Nullable || s.Optional || len(s.Transform) > 0 || s.Layout != "" || s.hasDefault
+	switch {
+	case _decl && _f.isDoc():
+		return nil, "文档规则不能声明required, nullable, optional, transform, layout, default"
+	case _decl && s.Rule != "":
+		return nil, "required, nullable, optional, transform, layout, default需要单独声明, 不能和rule一起使用"
+	case s.Required && s.Optional:
+		return nil, "required和optional不能同时设置"
+	case s.Rule == "" && !_decl:
+		return nil, "规则不能为空"
+	case _f.isDoc() && _f.Phase == "":
+		_f.Phase = kFormPhaseAfter
+	case _f.isDoc() && _f.Phase != kFormPhaseBefore && _f.Phase != kFormPhaseAfter:
+		return nil, fmt.Sprintf("phase[%s]只能是before或者after", _f.Phase)
+	case !_f.isDoc() && _f.Phase != "":
+		return nil, "只有__开头的文档规则可以设置phase"
+	}
+
+	if _u := _f.msg.Undeclared(kFormParams); len(_u) > 0 {
+		return nil, fmt.Sprintf("信息使用了未声明的占位符%v, 可用的占位符%v", _u, kFormParams)
+	}
+
+	var _fn []expr.OptionFn
+	if !_f.isDoc() {
+		_p, err := validator.ParsePath(field)
+		if err != nil {
+			return nil, err.Error()
+		}
+		_f.path = _p
+		_fn = append(_fn, expr.Env(validator.Env(&validator.KValidator{})))
+	}
+
+	if _f.isDecl() {
+		for _, _t := range _f.Transform {
+			if _, ok := kFormTransforms[_t]; !ok {
+				return nil, fmt.Sprintf("未知的转换[%s]", _t)
+			}
+		}
+
+		if _f.Layout == "" {
+			_f.Layout = time.RFC3339
+		}
+
+		if _f.Msg == "" {
+			_f.msg = kmsg.Parse(kFormRequiredMsg)
+		}
+		return _f, ""
+	}
+
+	p, err := expr.Parse(s.Rule, _fn...)
+	if err != nil {
+		return nil, fmt.Sprintf("规则[%s]解析失败: %s", s.Rule, err)
+	}
+	if err := validator.Check(p, _f.isDoc()); err != nil {
+		return nil, fmt.Sprintf("规则[%s]%s", s.Rule, err)
+	}
+	_f.Parser = p
+	return _f, ""
+}
+
+// Validator 校验json, 返回第一条失败规则的信息, 校验通过时返回空字符串
 func (t *KForms) Validator(form string, r io.Reader) string {
 	var _dt map[string]interface{}
 	g.AssertErr(g.Json.NewDecoder(r).Decode(&_dt), "json解析失败")
 
-	for k, v := range t.data[form] {
-		_s := ""
-		_b := true
+	if _e := t.Validate(form, _dt).First(); _e != nil {
+		return _e.Message
+	}
+	return ""
+}
+
+// Validate 执行表单的全部规则, 返回所有失败的规则和转换之后的文档, input不会被修改
+// 执行顺序: 字段声明的默认值和转换, phase为before的文档规则, 字段规则, phase为after的文档规则, 每一组内按照声明的顺序
+func (t *KForms) Validate(form string, input map[string]interface{}, opts ...ValidateOption) *ValidationResult {
+	var _cfg validateConfig
+	for _, o := range opts {
+		o(&_cfg)
+	}
+
+	_rules, ok := t.data[form]
+	g.AssertBool(!ok, "表单[%s]不存在", form)
+
+	_doc, _ := copyKFormDoc(input).(map[string]interface{})
+	if _doc == nil {
+		_doc = make(map[string]interface{})
+	}
+
+	_res := &ValidationResult{Form: form, Doc: _doc}
+	_failed := make(map[string]bool)
 
-		if strings.HasPrefix(k, "__") {
-			_b, _s = validator.KValidatorOf(_dt).Eval(v.Parser)
+	// 转换失败的值不再执行规则
+	_broken := make(map[string]bool)
+	_decls := make(map[string]*KForm)
+	for _, v := range _rules {
+		if !v.isDecl() {
+			continue
 		}
 
-		if _d, ok := _dt[k]; ok {
-			_b, _s = validator.KValidatorOf(_d).Do(v.Parser)
+		_decls[v.Field] = v
+		for _, _v := range resolveKFormPath(v.path, _doc) {
+			if _, err := v.transform(_v); err != nil {
+				_broken[_v.path] = true
+				_failed[_v.path] = true
+				_res.add(err)
+			}
 		}
+	}
+
+	_run := func(v *KForm) {
+		if v.isDoc() {
+			if _cfg.firstPerField && _failed[v.Field] {
+				return
+			}
 
-		if !_b {
-			return g.If(_s == "", v.Msg, fmt.Sprintf(v.Msg+", Err:%s", _s)).(string)
+			if _b, _s := validator.KValidatorOf(_doc).Eval(v.Parser); !_b {
+				_failed[v.Field] = true
+				_res.add(v.failure(v.Field, nil, _s))
+			}
+			return
 		}
 
+		// 通配符展开之后每个元素单独校验, 失败的字段为具体的路径
+		for _, _v := range resolveKFormPath(v.path, _doc) {
+			if _broken[_v.path] {
+				continue
+			}
+
+			if v.isDecl() {
+				if v.missing(_v) {
+					_failed[_v.path] = true
+					_res.add(v.failure(_v.path, _v.value, ""))
+				}
+				continue
+			}
+
+			if _decls[v.Field].skips(_v) || _cfg.firstPerField && _failed[_v.path] {
+				continue
+			}
+
+			if _b, _s := validator.Validate(_cfg.ctx, _doc, _v.value, v.Parser); !_b {
+				_failed[_v.path] = true
+				_res.add(v.failure(_v.path, _v.value, _s))
+			}
+		}
 	}
 
-	return ""
+	for _, _phase := range []string{kFormPhaseBefore, "", kFormPhaseAfter} {
+		for _, v := range _rules {
+			if v.Phase == _phase {
+				_run(v)
+			}
+		}
+	}
+
+	return _res
+}
+
+// Bind 校验input, 通过之后把转换之后的文档解码到out, out通常是结构体指针, 字段按照json tag对应
+// 类型不匹配时作为字段的校验错误返回, out中可能只解码了部分字段
+func (t *KForms) Bind(form string, input map[string]interface{}, out interface{}, opts ...ValidateOption) *ValidationResult {
+	_res := t.Validate(form, input, opts...)
+	if _res.OK() {
+		_res.Decode(out)
+	}
+	return _res
+}
+
+// failure 校验失败的信息, 信息中没有使用{err}时把err追加到信息后面
+func (t *KForm) failure(field string, value interface{}, err string) *ValidationError {
+	_msg := t.msg.RenderNamed(map[string]interface{}{"field": field, "value": value, "err": err})
+	if err != "" && !containsString(t.msg.Names(), "err") {
+		_msg += ", Err:" + err
+	}
+	return &ValidationError{Field: field, Rule: t.Rule, Code: t.Code, Message: _msg}
 }
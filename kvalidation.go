@@ -0,0 +1,120 @@
+package kweb
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/kooksee/kweb/internal/g"
+)
+
+// ValidationError 一条校验失败的规则
+type ValidationError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+func (t *ValidationError) Error() string {
+	return t.Field + ": " + t.Message
+}
+
+// ValidationResult 表单校验的结果, 包含全部失败的规则
+// Doc为执行了默认值和转换之后的文档, 不会渲染到json中
+type ValidationResult struct {
+	Form   string
+	Errors []*ValidationError
+	Doc    map[string]interface{}
+}
+
+func (t *ValidationResult) OK() bool {
+	return len(t.Errors) == 0
+}
+
+// First 第一条失败的规则, 校验通过时为nil
+func (t *ValidationResult) First() *ValidationError {
+	if t.OK() {
+		return nil
+	}
+	return t.Errors[0]
+}
+
+// Fields 按照字段分组的错误信息
+func (t *ValidationResult) Fields() map[string][]string {
+	_fs := make(map[string][]string)
+	for _, _e := range t.Errors {
+		_fs[_e.Field] = append(_fs[_e.Field], _e.Message)
+	}
+	return _fs
+}
+
+func (t *ValidationResult) add(e *ValidationError) {
+	t.Errors = append(t.Errors, e)
+}
+
+// Decode 把Doc按照json tag解码到out, 类型不匹配作为对应字段的校验错误加入Errors
+// 每个顶层字段单独解码, 一次报告全部不匹配的字段
+func (t *ValidationResult) Decode(out interface{}) bool {
+	for _, k := range sortedKeys(t.Doc) {
+		// 需要encoding/json的UnmarshalTypeError定位字段
+		_dt, err := json.Marshal(map[string]interface{}{k: t.Doc[k]})
+		if err == nil {
+			err = json.Unmarshal(_dt, out)
+		}
+		if err == nil {
+			continue
+		}
+
+		_e := &ValidationError{Field: k, Rule: "decode", Message: fmt.Sprintf("%s解码失败: %s", k, err)}
+
+		var _te *json.UnmarshalTypeError
+		if errors.As(err, &_te) {
+			if _te.Field != "" {
+				_e.Field = _te.Field
+			}
+			_e.Message = fmt.Sprintf("%s类型错误, 需要%s, 实际是%s", _e.Field, _te.Type, _te.Value)
+		}
+		t.add(_e)
+	}
+	return t.OK()
+}
+
+// MarshalJSON 渲染成前端可以直接按字段展示的格式
+//
+//	{"form": "user", "ok": false, "errors": [{"field": "name", "rule": "...", "message": "..."}], "fields": {"name": ["..."]}}
+func (t *ValidationResult) MarshalJSON() ([]byte, error) {
+	_es := t.Errors
+	if _es == nil {
+		_es = []*ValidationError{}
+	}
+
+	return g.Json.Marshal(map[string]interface{}{
+		"form":   t.Form,
+		"ok":     t.OK(),
+		"errors": _es,
+		"fields": t.Fields(),
+	})
+}
+
+type validateConfig struct {
+	firstPerField bool
+	ctx           context.Context
+}
+
+// ValidateOption Validate的选项
+type ValidateOption func(*validateConfig)
+
+// StopAtFirstPerField 每个字段只报告第一条失败的规则
+func StopAtFirstPerField() ValidateOption {
+	return func(c *validateConfig) {
+		c.firstPerField = true
+	}
+}
+
+// WithContext 传给注册的校验函数的context, 默认为context.Background()
+func WithContext(ctx context.Context) ValidateOption {
+	return func(c *validateConfig) {
+		c.ctx = ctx
+	}
+}
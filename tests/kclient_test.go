@@ -0,0 +1,96 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kooksee/kweb"
+	"github.com/kooksee/kweb/kclient"
+)
+
+func TestClientDecodeAndRetry(t *testing.T) {
+	var errs kweb.KErrors
+	loadErrors(t, &errs, "errors.toml")
+
+	calls := 0
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(kweb.ErrorHandler())
+	r.GET("/busy", func(c *gin.Context) {
+		if calls++; calls < 3 {
+			panic(errs.Get("user", "busy"))
+		}
+		c.JSON(200, gin.H{"name": "tom"})
+	})
+	r.GET("/disabled", func(c *gin.Context) {
+		calls++
+		panic(errs.Get("user", "disabled", "tom"))
+	})
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	cli := kclient.New(&errs)
+	cli.Backoff = time.Millisecond
+
+	var out struct{ Name string }
+	if err := cli.DoJSON(context.Background(), http.MethodGet, srv.URL+"/busy", nil, &out); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || out.Name != "tom" {
+		t.Fatalf("expected 3 calls and a decoded body, got %d %+v", calls, out)
+	}
+
+	calls = 0
+	err := cli.DoJSON(context.Background(), http.MethodGet, srv.URL+"/disabled", nil, nil)
+	if !errors.Is(err, &kweb.KError{Ns: "user", Name: "disabled"}) {
+		t.Fatalf("expected user.disabled, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("non retryable errors must not be retried, got %d calls", calls)
+	}
+
+	var ke *kweb.KError
+	if !errors.As(err, &ke) || ke.Status != 403 || ke.Msg != "用户tom已被禁用" {
+		t.Fatalf("unexpected error %#v", ke)
+	}
+}
+
+func TestClientRetryCanceled(t *testing.T) {
+	var errs kweb.KErrors
+	loadErrors(t, &errs, "errors.toml")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(kweb.ErrorHandler())
+	r.GET("/busy", func(c *gin.Context) {
+		panic(errs.Get("user", "busy"))
+	})
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	cli := kclient.New(&errs)
+	cli.Backoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := cli.DoJSON(ctx, http.MethodGet, srv.URL+"/busy", nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the context error, got %v", err)
+	}
+	if !errors.Is(err, &kweb.KError{Ns: "user", Name: "busy"}) {
+		t.Fatalf("expected the last response error to be attached, got %v", err)
+	}
+
+	var ke *kweb.KError
+	if !errors.As(err, &ke) || ke.Name != "busy" {
+		t.Fatalf("unexpected error %#v", ke)
+	}
+}
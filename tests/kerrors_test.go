@@ -0,0 +1,396 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kooksee/kweb"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// loadErrors 加载示例错误目录, 示例目录不能有问题
+func loadErrors(t *testing.T, errs *kweb.KErrors, files ...string) {
+	t.Helper()
+	for _, f := range files {
+		if ps := errs.FromPath(f); len(ps) != 0 {
+			t.Fatalf("%s has problems: %v", f, ps)
+		}
+	}
+	if ps := errs.Check(); len(ps) != 0 {
+		t.Fatalf("%v has problems: %v", files, ps)
+	}
+}
+
+func TestKErrorIs(t *testing.T) {
+	var errs kweb.KErrors
+	loadErrors(t, &errs, "errors.toml")
+
+	cause := errors.New("db down")
+	err := fmt.Errorf("load user: %w", errs.Get("error1", "errr_ccc", "hello").WithCause(cause))
+
+	if !errors.Is(err, &kweb.KError{Ns: "error1", Name: "errr_ccc"}) {
+		t.Fatalf("expected %v to match error1.errr_ccc", err)
+	}
+	if errors.Is(err, &kweb.KError{Ns: "error2", Name: "errr_ccc"}) {
+		t.Fatalf("expected %v not to match error2.errr_ccc", err)
+	}
+	if !errors.Is(err, &kweb.KError{Code: "20000"}) {
+		t.Fatalf("expected %v to match code 20000", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Fatalf("expected %v to wrap the cause", err)
+	}
+
+	var ke *kweb.KError
+	if !errors.As(err, &ke) || ke.Msg != "错误信息 hello" {
+		t.Fatalf("unexpected KError %#v", ke)
+	}
+
+	if s := fmt.Sprintf("%+v", ke); !strings.Contains(s, "caused by: db down") {
+		t.Fatalf("trace output misses the cause chain: %s", s)
+	}
+}
+
+func TestExecuteTrace(t *testing.T) {
+	var errs kweb.KErrors
+	loadErrors(t, &errs, "errors.toml")
+
+	cmd := &cobra.Command{Use: "demo", RunE: func(cmd *cobra.Command, args []string) error {
+		return fmt.Errorf("run demo: %w", errs.Get("error1", "errr_ccc", "x").WithCause(errors.New("db down")))
+	}}
+	cmd.SetArgs([]string{})
+
+	var out bytes.Buffer
+	code := 0
+	viper.Set(kweb.TraceFlag, true)
+	defer viper.Set(kweb.TraceFlag, false)
+
+	_ = kweb.Executor{Command: cmd, Exit: func(c int) { code = c }, Stderr: &out}.Execute()
+	s := out.String()
+	if code != 1 || !strings.Contains(s, "ERROR: run demo: 20000") || !strings.Contains(s, "TestExecuteTrace") || !strings.Contains(s, "caused by: db down") {
+		t.Fatalf("unexpected trace output (exit %d):\n%s", code, s)
+	}
+}
+
+func TestKErrorsLocalized(t *testing.T) {
+	errs := kweb.KErrors{Fallbacks: []string{"en"}}
+	loadErrors(t, &errs, "errors.toml")
+	loadErrors(t, &errs, "errors.en.toml")
+
+	if e := errs.GetLocalized("en-US", "error1", "errr_ccc", "x"); e.Msg != "error message x" {
+		t.Fatalf("unexpected en message %q", e.Msg)
+	}
+	if e := errs.GetLocalized("en", "error2", "errr_ccc"); e.Msg != "错误信息" {
+		t.Fatalf("expected fallback to zh, got %q", e.Msg)
+	}
+	if l := errs.MatchLocale("ja", "en-GB"); l != "en" {
+		t.Fatalf("unexpected locale %q", l)
+	}
+}
+
+func TestAcceptLanguages(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/langs", func(c *gin.Context) {
+		c.JSON(200, kweb.AcceptLanguages(c))
+	})
+
+	for header, want := range map[string]string{
+		"en;q=0.5, zh-CN":            `["zh-CN","en"]`,
+		"zh-CN, en;q=0, ja;q=-1, fr": `["zh-CN","fr"]`,
+		"en;q=0, *":                  `null`,
+	} {
+		req := httptest.NewRequest("GET", "/langs", nil)
+		req.Header.Set("Accept-Language", header)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if strings.TrimSpace(w.Body.String()) != want {
+			t.Fatalf("%q: got %s, want %s", header, w.Body.String(), want)
+		}
+	}
+}
+
+func TestKErrorsNamedParams(t *testing.T) {
+	var errs kweb.KErrors
+	loadErrors(t, &errs, "errors.toml")
+	loadErrors(t, &errs, "errors.en.toml")
+
+	if e := errs.Get("user", "not_found", "tom", 7); e.Msg != "用户tom不存在, id:7" {
+		t.Fatalf("unexpected zh message %q", e.Msg)
+	}
+	if e := errs.GetLocalized("en", "user", "not_found", "tom", 7); e.Msg != "user 7 (tom) not found" {
+		t.Fatalf("positional args must follow the declared order, got %q", e.Msg)
+	}
+
+	args := struct {
+		ID   int `json:"id"`
+		Name string
+	}{ID: 8, Name: "amy"}
+	if e := errs.GetLocalized("en", "user", "not_found", args); e.Msg != "user 8 (amy) not found" {
+		t.Fatalf("unexpected message from struct args %q", e.Msg)
+	}
+}
+
+func TestKErrorsProblems(t *testing.T) {
+	var errs kweb.KErrors
+	ps := append(errs.FromPath("errors_bad.toml"), errs.Check()...)
+
+	var msgs []string
+	for _, p := range ps {
+		msgs = append(msgs, p.Error())
+	}
+	all := strings.Join(msgs, "\n")
+
+	for _, want := range []string{"order.bad_code", "order.too_short", "code[40001]已经被"} {
+		if !strings.Contains(all, want) {
+			t.Fatalf("expected a problem about %s, got:\n%s", want, all)
+		}
+	}
+
+	e := errs.Get("order", "too_short")
+	if e.Code != kweb.UnknownCode {
+		t.Fatalf("expected unknown error for a malformed entry, got %v", e)
+	}
+	if e := errs.Get("nope", "nope"); e.Code != kweb.UnknownCode {
+		t.Fatalf("expected unknown error for a missing entry, got %v", e)
+	}
+}
+
+func TestGenErrors(t *testing.T) {
+	var errs kweb.KErrors
+	loadErrors(t, &errs, "errors.toml")
+
+	src, err := kweb.GenErrors(&errs, "errs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := parser.ParseFile(token.NewFileSet(), "errs_gen.go", src, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %s\n%s", err, src)
+	}
+
+	sigs := make(map[string]string)
+	for _, d := range f.Decls {
+		fn, ok := d.(*ast.FuncDecl)
+		if !ok {
+			continue
+		}
+
+		var params []string
+		seen := make(map[string]bool)
+		for _, p := range fn.Type.Params.List {
+			for _, n := range p.Names {
+				if seen[n.Name] {
+					t.Fatalf("duplicate param %s in %s", n.Name, fn.Name.Name)
+				}
+				seen[n.Name] = true
+				params = append(params, n.Name+" "+types.ExprString(p.Type))
+			}
+		}
+		sigs[fn.Name.Name] = "(" + strings.Join(params, ", ") + ") " + types.ExprString(fn.Type.Results.List[0].Type)
+	}
+
+	for name, want := range map[string]string{
+		"ErrError1ErrrCcc": "(arg1 string) *kerrors.KError",
+		"ErrError2ErrrCcc": "() *kerrors.KError",
+		"ErrUserNotFound":  "(name interface{}, id interface{}) *kerrors.KError",
+	} {
+		if sigs[name] != want {
+			t.Fatalf("unexpected signature of %s: %q, want %q", name, sigs[name], want)
+		}
+	}
+	if !strings.Contains(string(src), `return Errs.Get("user", "not_found", name, id)`) {
+		t.Fatalf("generated code misses the call to Errs.Get:\n%s", src)
+	}
+
+	// 转换成Go标识符之后重名的占位符和带序号的verb不能生成参数
+	for _, bad := range []string{
+		"[user]\nnot_found = [\"30001\", \"用户{user_id}不存在, {userId}\"]\n",
+		"[user]\nnot_found = [\"30001\", \"用户%[1]s不存在, %[1]s\"]\n",
+	} {
+		var errs kweb.KErrors
+		if ps := errs.FromFS(fstest.MapFS{"errors.toml": {Data: []byte(bad)}}, "*.toml"); len(ps) != 0 {
+			t.Fatal(ps)
+		}
+		if _, err := kweb.GenErrors(&errs, "errs"); err == nil {
+			t.Fatalf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	var errs kweb.KErrors
+	loadErrors(t, &errs, "errors.toml")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(kweb.ErrorHandler())
+	r.GET("/panic", func(c *gin.Context) {
+		panic(errs.Get("user", "disabled", "tom"))
+	})
+	r.GET("/error", func(c *gin.Context) {
+		_ = c.Error(fmt.Errorf("call upstream: %w", errs.Get("user", "busy")))
+	})
+	r.GET("/legacy", func(c *gin.Context) {
+		panic(errs.Get("error1", "errr_ccc", "x"))
+	})
+
+	for path, want := range map[string]struct {
+		status int
+		body   string
+	}{
+		"/panic":  {403, `{"code":"30002","msg":"用户tom已被禁用"}`},
+		"/error":  {503, `{"code":"30003","msg":"服务繁忙"}`},
+		"/legacy": {500, `{"code":"20000","msg":"错误信息 x"}`},
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != want.status || strings.TrimSpace(w.Body.String()) != want.body {
+			t.Fatalf("%s: got %d %s", path, w.Code, w.Body.String())
+		}
+	}
+
+	if !errs.Get("user", "busy").Retryable {
+		t.Fatal("user.busy should be retryable")
+	}
+}
+
+func TestKErrorsFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"errors/order.toml":      {Data: []byte("[order]\nnot_found = [\"40001\", \"订单不存在\"]\n")},
+		"errors/order.en.toml":   {Data: []byte("[order]\nnot_found = [\"40001\", \"order not found\"]\n")},
+		"errors/user.toml":       {Data: []byte("# 用户\n[user]\nnot_found = [\"40002\", \"用户不存在\"]\n\n[order]\nnot_found = [\"40003\", \"订单不存在\"]\ndup = [\"40002\", \"重复\"]\n")},
+		"errors/user.auth.toml":  {Data: []byte("[auth]\nexpired = [\"40004\", \"登录已过期\"]\n")},
+		"errors/user.api.toml":   {Data: []byte("[api]\nlimited = [\"40005\", \"请求太频繁\"]\n")},
+		"errors/order.v2.toml":   {Data: []byte("[order_v2]\nclosed = [\"40006\", \"订单已关闭\"]\n")},
+		"errors/user.zh_CN.toml": {Data: []byte("[user]\nnot_found = [\"40002\", \"用户不存在\"]\n")},
+	}
+
+	var errs kweb.KErrors
+	var msgs []string
+	for _, p := range append(errs.FromFS(fsys, "errors/*.toml"), errs.Check()...) {
+		msgs = append(msgs, p.Error())
+	}
+	all := strings.Join(msgs, "\n")
+
+	for _, want := range []string{
+		"errors/user.toml:6: order.not_found: 语言[zh]的code[40003]与errors/order.en.toml:2定义的code[40001]不一致",
+		"errors/user.toml:6: order.not_found: 语言[zh]的定义与errors/order.toml:2冲突",
+		"code[40002]已经被errors/user.toml:7的[order.dup]使用",
+	} {
+		if !strings.Contains(all, want) {
+			t.Fatalf("expected %q in:\n%s", want, all)
+		}
+	}
+
+	if e := errs.GetLocalized("en", "order", "not_found"); e.Msg != "order not found" {
+		t.Fatalf("unexpected message %q", e.Msg)
+	}
+	if ls := strings.Join(errs.Locales(), ","); ls != "en,zh,zh-cn" {
+		t.Fatalf("unexpected locales %s", ls)
+	}
+
+	// 后缀不是语言的文件属于默认语言
+	for _, k := range [][3]string{{"auth", "expired", "登录已过期"}, {"api", "limited", "请求太频繁"}, {"order_v2", "closed", "订单已关闭"}} {
+		if e := errs.Get(k[0], k[1]); e.Msg != k[2] {
+			t.Fatalf("unexpected message %q for %s.%s", e.Msg, k[0], k[1])
+		}
+	}
+}
+
+func TestExportErrors(t *testing.T) {
+	var errs kweb.KErrors
+	loadErrors(t, &errs, "errors.toml")
+	loadErrors(t, &errs, "errors.en.toml")
+
+	ts, err := kweb.ExportErrors(&errs, "ts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`UserNotFound = "30001",`, `| "user.disabled"`, `status: 403`} {
+		if !strings.Contains(string(ts), want) {
+			t.Fatalf("ts export misses %q:\n%s", want, ts)
+		}
+	}
+
+	md, err := kweb.ExportErrors(&errs, "markdown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(md), "| user | not_found | 30001 | 500 | false | 用户{name}不存在, id:{id} | user {id} ({name}) not found |") {
+		t.Fatalf("unexpected markdown:\n%s", md)
+	}
+
+	if _, err := kweb.ExportErrors(&errs, "xml"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+
+	// user_a.b和user.a_b的枚举名都是UserAB
+	var dup kweb.KErrors
+	if ps := dup.FromFS(fstest.MapFS{"errors.toml": {Data: []byte("[user_a]\nb = [\"1\", \"x\"]\n\n[user]\na_b = [\"2\", \"y\"]\n")}}, "*.toml"); len(ps) != 0 {
+		t.Fatal(ps)
+	}
+	if _, err := kweb.ExportErrors(&dup, "ts"); err == nil || !strings.Contains(err.Error(), "UserAB") {
+		t.Fatalf("expected an enum name collision, got %v", err)
+	}
+}
+
+func TestKErrorsCodeRanges(t *testing.T) {
+	fsys := fstest.MapFS{
+		"errors.toml": {Data: []byte(`[__ranges]
+user = ["30000", "30009"]
+order = [30005, 30100]
+
+[user]
+not_found = ["30000", "用户不存在"]
+disabled = ["30002", "用户已被禁用"]
+deleted = ["40000", "用户已被删除"]
+
+[pay]
+refund = ["30050", "退款失败"]
+`)},
+	}
+
+	var errs kweb.KErrors
+	var msgs []string
+	for _, p := range append(errs.FromFS(fsys, "*.toml"), errs.Check()...) {
+		msgs = append(msgs, p.Error())
+	}
+	all := strings.Join(msgs, "\n")
+
+	for _, want := range []string{
+		"errors.toml:3: __ranges.order: 范围[30005, 30100]与errors.toml:2的[user]范围[30000, 30009]重叠",
+		"errors.toml:8: user.deleted: code[40000]不在errors.toml:2声明的范围[30000, 30009]内",
+		"errors.toml:11: pay.refund: code[30050]在errors.toml:3声明的[order]范围[30005, 30100]内",
+	} {
+		if !strings.Contains(all, want) {
+			t.Fatalf("expected %q in:\n%s", want, all)
+		}
+	}
+
+	if code, err := errs.NextCode("user"); err != nil || code != "30001" {
+		t.Fatalf("unexpected next code %q %v", code, err)
+	}
+	if _, err := errs.NextCode("error1"); err == nil {
+		t.Fatal("expected an error for a namespace without a range")
+	}
+
+	// 示例目录中user的范围
+	var sample kweb.KErrors
+	loadErrors(t, &sample, "errors.toml")
+	if code, err := sample.NextCode("user"); err != nil || code != "30004" {
+		t.Fatalf("unexpected next code %q %v", code, err)
+	}
+}
@@ -0,0 +1,579 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kooksee/kweb"
+	"github.com/kooksee/kweb/internal/g"
+)
+
+func TestKFormsValidate(t *testing.T) {
+	var forms kweb.KForms
+	forms.FromPath("forms.toml")
+
+	res := forms.Validate("user", map[string]interface{}{"name": "t0m", "email": "x", "age": 10.0})
+	if len(res.Errors) != 3 {
+		t.Fatalf("expected every rule to be reported, got %+v", res.Errors)
+	}
+
+	fields := res.Fields()
+	if fields["name"][0] != "姓名name只能是字母" || fields["email"][0] != "邮箱格式错误" || fields["__adult"][0] != "未成年" {
+		t.Fatalf("unexpected messages %v", fields)
+	}
+
+	dt, err := g.Json.Marshal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(dt), `"code":"10001"`) || !strings.Contains(string(dt), `"ok":false`) {
+		t.Fatalf("unexpected json %s", dt)
+	}
+
+	if res := forms.Validate("user", map[string]interface{}{"name": "tom", "email": "tom@example.com", "age": 20.0}); !res.OK() {
+		t.Fatalf("expected a valid form, got %+v", res.Errors)
+	}
+
+	if msg := forms.Validator("user", strings.NewReader(`{"name": "tom", "email": "x", "age": 20}`)); msg != "邮箱格式错误" {
+		t.Fatalf("unexpected first message %q", msg)
+	}
+}
+
+func TestKFormsRuleOrder(t *testing.T) {
+	var forms kweb.KForms
+	forms.FromPath("forms.toml")
+
+	input := map[string]interface{}{"name": "t0m!", "email": ""}
+	for i := 0; i < 10; i++ {
+		res := forms.Validate("user.signup", input)
+
+		var got []string
+		for _, e := range res.Errors {
+			got = append(got, e.Message)
+		}
+
+		want := []string{"邮箱不能为空", "姓名只能是字母或数字", "姓名只能是字母", "邮箱格式错误"}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Fatalf("unexpected order %v", got)
+		}
+	}
+
+	res := forms.Validate("user.signup", input, kweb.StopAtFirstPerField())
+	if len(res.Fields()["name"]) != 1 {
+		t.Fatalf("expected only the first failure of name, got %v", res.Fields())
+	}
+}
+
+func TestKFormsParams(t *testing.T) {
+	var forms kweb.KForms
+	forms.FromPath("forms.toml")
+
+	ok := map[string]interface{}{"age": 18.0, "nick": "tom", "email": "tom@example.com", "role": "user", "tags": []interface{}{"go", "web"}}
+	if res := forms.Validate("profile", ok); !res.OK() {
+		t.Fatalf("expected a valid form, got %+v", res.Errors)
+	}
+
+	bad := map[string]interface{}{"age": 17.5, "nick": "Tom", "email": "tom", "role": "root", "tags": []interface{}{}}
+	res := forms.Validate("profile", bad)
+	if len(res.Errors) != 5 {
+		t.Fatalf("expected every field to fail, got %+v", res.Errors)
+	}
+
+	res = forms.Validate("profile", map[string]interface{}{"age": true})
+	if e := res.First(); e == nil || !strings.Contains(e.Message, "Err:") {
+		t.Fatalf("expected a type error for a boolean age, got %+v", res.Errors)
+	}
+
+	// 信息中使用了{err}时不再追加错误
+	res = forms.Validate("coupon", map[string]interface{}{"code": true})
+	if e := res.First(); e == nil || e.Message != "优惠码无效: 字段类型[bool]没有长度" {
+		t.Fatalf("unexpected message %+v", res.Errors)
+	}
+
+	// HasLengthOf比较长度, 字符串函数不接受其他类型, 前面的函数失败不影响后面的函数
+	text := loadForms(t, `[text]
+code = ['HasLengthOf(5)', "长度必须是5"]
+note = ['IsEmail() || Excludes("x")', "不能包含x"]
+sym = ['ExcludesAll("<")', "不能包含<"]
+`)
+	if res := text.Validate("text", map[string]interface{}{"code": "abcde", "note": "abc", "sym": "a"}); !res.OK() {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+	if res := text.Validate("text", map[string]interface{}{"code": 5.0, "note": "xyz", "sym": 3.0}); len(res.Errors) != 3 {
+		t.Fatalf("expected every field to fail, got %+v", res.Errors)
+	}
+}
+
+func TestKFormsNestedPath(t *testing.T) {
+	var forms kweb.KForms
+	forms.FromPath("forms.toml")
+
+	var input map[string]interface{}
+	if err := g.Json.Unmarshal([]byte(`{
+		"address": {"city": "beijing1"},
+		"items": [{"qty": 1}, {"qty": 2}, {"qty": 3}, {"qty": 0}],
+		"tags": ["go", "", "web"]
+	}`), &input); err != nil {
+		t.Fatal(err)
+	}
+
+	fields := forms.Validate("order", input).Fields()
+	if len(fields) != 3 {
+		t.Fatalf("unexpected failures %v", fields)
+	}
+	if fields["address.city"][0] != "城市address.city只能是字母" || fields["items[3].qty"][0] != "items[3].qty数量至少为1" || fields["tags[1]"][0] != "tags[1]长度错误" {
+		t.Fatalf("unexpected failures %v", fields)
+	}
+
+	if res := forms.Validate("order", map[string]interface{}{}); !res.OK() {
+		t.Fatalf("expected missing paths to be skipped, got %+v", res.Errors)
+	}
+}
+
+func TestKFormsRequired(t *testing.T) {
+	var forms kweb.KForms
+	forms.FromPath("forms.toml")
+
+	fields := forms.Validate("account", map[string]interface{}{"email": nil, "phone": nil, "nick": ""}).Fields()
+	if len(fields) != 2 || fields["name"][0] != "name必填" || fields["email"][0] != "email是必填字段" {
+		t.Fatalf("unexpected failures %v", fields)
+	}
+
+	fields = forms.Validate("account", map[string]interface{}{"name": "", "email": "x", "phone": "abc", "nick": "ab"}).Fields()
+	if len(fields) != 3 || len(fields["name"]) != 1 || fields["phone"][0] != "电话只能是数字" || fields["nick"][0] != "昵称长度错误" {
+		t.Fatalf("unexpected failures %v", fields)
+	}
+}
+
+func TestKFormsMiddleware(t *testing.T) {
+	var forms kweb.KForms
+	forms.FromPath("forms.toml")
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/users/:id", forms.Form("user.signup"), func(c *gin.Context) {
+		dt := kweb.FormOf(c)
+		c.JSON(200, gin.H{"id": dt["id"], "name": dt["name"], "lang": dt["lang"]})
+	})
+
+	for _, tc := range []struct {
+		contentType string
+		body        string
+		status      int
+		contains    string
+	}{
+		{"application/json", `{"name": "tom", "email": "tom@example.com"}`, 200, `{"id":"7","lang":"zh","name":"tom"}`},
+		{"application/x-www-form-urlencoded", `name=tom&email=tom%40example.com`, 200, `{"id":"7","lang":"zh","name":"tom"}`},
+		{"application/json", `{"name": "t0m!", "email": "tom@example.com"}`, 400, `"ok":false`},
+		{"application/json", `{"name": `, 400, `请求解析失败`},
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/users/7?lang=zh", strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", tc.contentType)
+		r.ServeHTTP(w, req)
+
+		if w.Code != tc.status || !strings.Contains(w.Body.String(), tc.contains) {
+			t.Fatalf("%s %s: got %d %s", tc.contentType, tc.body, w.Code, w.Body.String())
+		}
+	}
+	// 表单名错误时注册路由就失败, 而不是每个请求都panic
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for an unknown form")
+		}
+	}()
+	forms.Form("user.sign_up")
+}
+
+func TestKFormsTransform(t *testing.T) {
+	var forms kweb.KForms
+	forms.FromPath("forms.toml")
+
+	input := map[string]interface{}{"name": " Alice ", "vip": "true", "birthday": "2000-01-02"}
+	res := forms.Validate("member", input)
+	if !res.OK() {
+		t.Fatalf("expected a valid form, got %+v", res.Errors)
+	}
+
+	doc := res.Doc
+	if doc["name"] != "alice" || doc["age"] != int64(18) || doc["vip"] != true || input["name"] != " Alice " {
+		t.Fatalf("unexpected doc %v", doc)
+	}
+	if b, ok := doc["birthday"].(time.Time); !ok || b.Year() != 2000 || b.Month() != 1 || b.Day() != 2 {
+		t.Fatalf("unexpected birthday %v", doc["birthday"])
+	}
+
+	// birthday按照声明的layout解析, 不接受RFC3339
+	fields := forms.Validate("member", map[string]interface{}{"name": "bob", "birthday": "2000-01-02T00:00:00Z"}).Fields()
+	if len(fields["birthday"]) != 1 || !strings.Contains(fields["birthday"][0], "to_time") {
+		t.Fatalf("expected a layout failure, got %v", fields)
+	}
+
+	fields = forms.Validate("member", map[string]interface{}{"name": "bob", "age": "17"}).Fields()
+	if len(fields) != 1 || fields["age"][0] != "未成年" {
+		t.Fatalf("unexpected failures %v", fields)
+	}
+
+	fields = forms.Validate("member", map[string]interface{}{"name": "bob", "age": "x"}).Fields()
+	if len(fields["age"]) != 1 || !strings.Contains(fields["age"][0], "to_int") {
+		t.Fatalf("expected a conversion failure, got %v", fields)
+	}
+
+	// 默认值是副本, 修改结果不影响下一次校验
+	post := loadForms(t, `[post]
+tags = [{default = ["news"]}]
+`)
+	res = post.Validate("post", map[string]interface{}{})
+	res.Doc["tags"].([]interface{})[0] = "changed"
+	if tags := post.Validate("post", map[string]interface{}{}).Doc["tags"].([]interface{}); tags[0] != "news" {
+		t.Fatalf("default value was shared: %v", tags)
+	}
+}
+
+func TestKFormsBind(t *testing.T) {
+	var forms kweb.KForms
+	forms.FromPath("forms.toml")
+
+	var m struct {
+		Name     string    `json:"name"`
+		Age      int       `json:"age"`
+		Vip      bool      `json:"vip"`
+		Birthday time.Time `json:"birthday"`
+	}
+	res := forms.Bind("member", map[string]interface{}{"name": " Alice ", "age": "20", "birthday": "2000-01-02"}, &m)
+	if !res.OK() || m.Name != "alice" || m.Age != 20 || m.Vip || m.Birthday.Year() != 2000 {
+		t.Fatalf("unexpected result %+v %+v", res.Errors, m)
+	}
+
+	var bad struct {
+		Name int    `json:"name"`
+		Age  string `json:"age"`
+	}
+	res = forms.Bind("member", map[string]interface{}{"name": "alice", "age": 20.0}, &bad)
+	fields := res.Fields()
+	if len(fields) != 2 || len(fields["name"]) != 1 || len(fields["age"]) != 1 {
+		t.Fatalf("expected type errors on name and age, got %v", fields)
+	}
+}
+
+func TestKFormsCheck(t *testing.T) {
+	var forms kweb.KForms
+
+	var msgs []string
+	for _, p := range append(forms.FromDir("forms_lint"), forms.Check()...) {
+		msgs = append(msgs, p.Error())
+	}
+
+	for _, want := range []string{
+		"forms_lint/b.toml:3: user.phone: 规则[NoSuchFunc()]解析失败",
+		"forms_lint/b.toml:2: user.name: 覆盖了forms_lint/a.toml:2中的规则",
+		"forms_lint/a.toml:3: user.email: 规则[IsEmail()]重复",
+		"forms_lint/a.toml:4: user.nick: 字段没有规则, nullable和optional不起作用",
+		"forms_lint/a.toml:5: user.age: 规则[1 + 2]规则结果的类型是",
+		"forms_lint/a.toml:6: user.zone: 规则[TZ(\"Mars/Base\")]函数[TZ]的第1个参数[Mars/Base]错误",
+		"forms_lint/a.toml:7: user.slug: 规则[Match(\"[a-z\")]函数[Match]的第1个参数[[a-z]错误",
+	} {
+		found := false
+		for _, m := range msgs {
+			found = found || strings.HasPrefix(m, want)
+		}
+		if !found {
+			t.Fatalf("missing problem %q in %v", want, msgs)
+		}
+	}
+
+	forms = kweb.KForms{}
+	forms.FromPath("forms.toml")
+	if ps := forms.Check(); len(ps) != 0 {
+		t.Fatalf("unexpected problems %v", ps)
+	}
+}
+
+func TestKFormsSchema(t *testing.T) {
+	var forms kweb.KForms
+	forms.FromPath("forms.toml")
+
+	dt, err := kweb.ExportFormSchema(&forms, "profile")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var schema struct {
+		Schema     string `json:"$schema"`
+		Properties map[string]map[string]interface{}
+	}
+	if err := g.Json.Unmarshal(dt, &schema); err != nil {
+		t.Fatal(err)
+	}
+	age, nick, role := schema.Properties["age"], schema.Properties["nick"], schema.Properties["role"]
+	if schema.Schema != "https://json-schema.org/draft/2020-12/schema" || age["minimum"] != 18.0 || age["maximum"] != 120.0 ||
+		nick["pattern"] != "^[a-z]+$" || nick["maxLength"] != 32.0 || len(role["enum"].([]interface{})) != 2 {
+		t.Fatalf("unexpected schema %s", dt)
+	}
+
+	src, err := kweb.ImportFormSchema(dt, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "profile.toml")
+	if err := ioutil.WriteFile(file, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var imported kweb.KForms
+	imported.FromPath(file)
+
+	bad := map[string]interface{}{"age": 17.5, "nick": "Tom", "email": "tom", "role": "root", "tags": []interface{}{}}
+	if a, b := forms.Validate("profile", bad).Fields(), imported.Validate("profile", bad).Fields(); len(a) != 5 || len(b) != len(a) {
+		t.Fatalf("imported form differs: %v %v\n%s", a, b, src)
+	}
+
+	// layout在导出和导入之后保持不变
+	dt, err = kweb.ExportFormSchema(&forms, "member")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.Json.Unmarshal(dt, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if schema.Properties["birthday"]["format"] != "date" {
+		t.Fatalf("expected a date format, got %s", dt)
+	}
+
+	src, err = kweb.ImportFormSchema(dt, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(src), `layout = "2006-01-02"`) {
+		t.Fatalf("missing layout in\n%s", src)
+	}
+	if err := ioutil.WriteFile(file, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var member kweb.KForms
+	if ps := member.FromDir(filepath.Dir(file)); len(ps) != 0 {
+		t.Fatalf("imported member form has problems %v\n%s", ps, src)
+	}
+
+	src, err = kweb.ImportFormSchema([]byte(`{
+		"title": "signup",
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string", "minLength": 3, "maxLength": 8, "pattern": "^[a-z]+$"},
+			"age": {"type": "integer", "minimum": 18},
+			"email": {"type": ["string", "null"], "format": "email"}
+		}
+	}`), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"[signup]",
+		`age = [{rule = "IsType(\"integer\")", msg = "{field}的类型必须是integer"}, {rule = "Gte(18)", msg = "{field}不能小于18"}]`,
+		`email = [{nullable = true}, {rule = "IsType(\"string\")", msg = "{field}的类型必须是string"}, {rule = "IsEmail()", msg = "{field}不是有效的email"}]`,
+		`name = [{required = true}, {rule = "IsType(\"string\")", msg = "{field}的类型必须是string"}, {rule = "Len(3, 8)", msg = "{field}长度必须在3到8之间"}, {rule = "Match(\"^[a-z]+$\")", msg = "{field}格式错误"}]`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Fatalf("missing %q in\n%s", want, src)
+		}
+	}
+
+	// type限制值的类型, 长度为18的字符串不能通过minimum
+	signup := loadForms(t, string(src))
+	if fields := signup.Validate("signup", map[string]interface{}{"name": "tom", "age": "xxxxxxxxxxxxxxxxxx"}).Fields(); len(fields["age"]) == 0 {
+		t.Fatalf("expected a type error for age, got %v", fields)
+	}
+}
+
+func TestKFormsGenTS(t *testing.T) {
+	var forms kweb.KForms
+	forms.FromPath("forms.toml")
+
+	dt, ps, err := kweb.GenFormsTS(&forms, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src := string(dt)
+	for _, s := range []string{`Gte(v, 18) && Lte(v, 120)`, `Match(v, new RegExp("^[a-z]+$", "u"))`, `OneOf(v, "admin", "user")`,
+		`Test(v, P.IsAlpha, false)`, `"transform":["trim","lower"]`, `"msg":"年龄必须在18到120之间"`, "export function validate(", `render(r.msg || "", s.path, s.value, lastErr)`, `s + ", Err:" + err`} {
+		if !strings.Contains(src, s) {
+			t.Fatalf("missing %s in\n%s", s, src)
+		}
+	}
+
+	// 文档规则和to_time只在服务端执行
+	var msgs []string
+	for _, p := range ps {
+		msgs = append(msgs, p.Form+"."+p.Field+": "+p.Msg)
+	}
+	if all := strings.Join(msgs, "\n"); len(ps) != 4 || !strings.Contains(all, "user.__adult") || !strings.Contains(all, "转换[to_time]只在服务端执行") {
+		t.Fatalf("unexpected problems\n%s", all)
+	}
+
+	if _, _, err := kweb.GenFormsTS(&forms, "nope"); err == nil {
+		t.Fatal("expected error for unknown form")
+	}
+}
+
+// loadForms 从src加载表单, src不能有问题
+func loadForms(t *testing.T, src string) *kweb.KForms {
+	t.Helper()
+	var forms kweb.KForms
+	if ps := forms.FromFS(fstest.MapFS{"forms.toml": {Data: []byte(src)}}, "*.toml"); len(ps) != 0 {
+		t.Fatalf("forms have problems: %v", ps)
+	}
+	return &forms
+}
+
+type ctxKey struct{}
+
+func TestKFormsRegisterValidator(t *testing.T) {
+	kweb.RegisterValidator("IsOrderNo", func(v string) bool { return strings.HasPrefix(v, "NO") })
+	kweb.RegisterValidator("Quota", func(ctx context.Context, v float64, n float64) (bool, string) {
+		return v <= n, fmt.Sprintf("%v超过%v", ctx.Value(ctxKey{}), n)
+	})
+
+	forms := loadForms(t, "[order]\nno = ['IsOrderNo()', \"订单号格式错误\"]\nqty = ['Quota(10)', \"数量超过限制\"]\n")
+
+	ctx := kweb.WithContext(context.WithValue(context.Background(), ctxKey{}, "user"))
+	res := forms.Validate("order", map[string]interface{}{"no": "X1", "qty": 11.0}, ctx)
+	fields := res.Fields()
+	if len(res.Errors) != 2 || fields["no"][0] != "订单号格式错误" || fields["qty"][0] != "数量超过限制, Err:user超过10" {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+
+	if res := forms.Validate("order", map[string]interface{}{"no": "NO1", "qty": 3.0}); !res.OK() {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+
+	// 参数类型在加载时检查
+	var bad kweb.KForms
+	if ps := bad.FromFS(fstest.MapFS{"order.toml": {Data: []byte("[order]\nqty = ['Quota(\"x\")', \"数量超过限制\"]\n")}}, "*.toml"); len(ps) != 1 {
+		t.Fatalf("expected a type error, got %v", ps)
+	}
+}
+
+func TestKFormsDB(t *testing.T) {
+	app := kweb.GetApp()
+	app.InitDb(&kweb.DbConfig{Schema: "sqlite3", Name: "forms", DbUrl: filepath.Join(t.TempDir(), "forms.db")})
+	t.Cleanup(func() { _ = app.DB("forms").Close() })
+
+	db := app.DB("forms")
+	for _, q := range []string{
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)",
+		"INSERT INTO users (id, email) VALUES (1, 'a@b.co'), (2, 'c@d.co')",
+	} {
+		if _, err := db.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	forms := loadForms(t, `[signup]
+email = ['Unique("users", "email", "forms")', "邮箱已经注册"]
+friend_ids = ['Exists("users", "id", "forms")', "用户不存在"]
+bad = ['Exists("users; DROP TABLE users", "id", "forms")', "表名错误"]
+`)
+
+	res := forms.Validate("signup", map[string]interface{}{"email": "a@b.co", "friend_ids": []interface{}{1.0, 2.0, 3.0}, "bad": 1.0})
+	fields := res.Fields()
+	if len(res.Errors) != 3 || !strings.Contains(fields["friend_ids"][0], "有1个值不存在") || !strings.Contains(fields["bad"][0], "格式错误") {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+
+	if res := forms.Validate("signup", map[string]interface{}{"email": "new@b.co", "friend_ids": []interface{}{1.0, 2.0, 2.0}}); !res.OK() {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+
+	// 1和"1"在数据库中是同一个值, 分别比较
+	if res := forms.Validate("signup", map[string]interface{}{"email": "new@b.co", "friend_ids": []interface{}{1.0, "1"}}); !res.OK() {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+	res = forms.Validate("signup", map[string]interface{}{"email": "new@b.co", "friend_ids": []interface{}{1.0, "3"}})
+	if fields := res.Fields(); len(res.Errors) != 1 || !strings.Contains(fields["friend_ids"][0], "有1个值不存在") {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+}
+
+func TestKFormsDates(t *testing.T) {
+	forms := loadForms(t, `[event]
+day = ['IsDate("2006-01-02")', "日期格式错误"]
+start_at = ['TZ("Asia/Shanghai") && After("now") && Within("+720h")', "开始时间必须在30天之内"]
+end_at = ['After(Field("start_at"))', "结束时间必须在开始时间之后"]
+close_at = ['After(Field("slots[1].end_at"))', "关闭时间必须在最后一个时段之后"]
+`)
+
+	start := time.Now().Add(48 * time.Hour)
+	ok := map[string]interface{}{
+		"day":      "2026-10-18",
+		"start_at": start.Format(time.RFC3339),
+		"end_at":   start.Add(time.Hour).Format(time.RFC3339),
+		"close_at": start.Add(3 * time.Hour).Format(time.RFC3339),
+		"slots": []interface{}{
+			map[string]interface{}{"end_at": start.Add(time.Hour).Format(time.RFC3339)},
+			map[string]interface{}{"end_at": start.Add(2 * time.Hour).Format(time.RFC3339)},
+		},
+	}
+	if res := forms.Validate("event", ok); !res.OK() {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+
+	bad := map[string]interface{}{
+		"day":      "2026/10/18",
+		"start_at": start.Add(60 * 24 * time.Hour).Format("2006-01-02 15:04:05"),
+		"end_at":   start.Add(-time.Hour).Format(time.RFC3339),
+		"close_at": start.Add(3 * time.Hour).Format(time.RFC3339),
+		"slots": []interface{}{
+			map[string]interface{}{"end_at": start.Add(time.Hour).Format(time.RFC3339)},
+			map[string]interface{}{"end_at": start.Add(4 * time.Hour).Format(time.RFC3339)},
+		},
+	}
+	if fields := forms.Validate("event", bad).Fields(); len(fields) != 4 {
+		t.Fatalf("expected every field to fail, got %v", fields)
+	}
+}
+
+func TestKFormsChina(t *testing.T) {
+	forms := loadForms(t, `[company]
+mobile = ['IsChinaMobile()', "手机号错误"]
+id_card = ['IsChinaIDCard()', "身份证号错误"]
+uscc = ['IsUSCC()', "统一社会信用代码错误"]
+bank_card = ['IsBankCard()', "银行卡号错误"]
+postcode = ['IsChinaPostcode()', "邮编错误"]
+plate = ['IsChinaPlate()', "车牌号错误"]
+`)
+
+	ok := map[string]interface{}{
+		"mobile":    "+86 13800138000",
+		"id_card":   "11010519491231002X",
+		"uscc":      "91350100M000100Y43",
+		"bank_card": "4111 1111 1111 1111",
+		"postcode":  "100000",
+		"plate":     "粤BD12345",
+	}
+	if res := forms.Validate("company", ok); !res.OK() {
+		t.Fatalf("unexpected errors %+v", res.Errors)
+	}
+
+	bad := map[string]interface{}{
+		"mobile":    "12800138000",
+		"id_card":   "110105194912310021",
+		"uscc":      "91350100M000100Y44",
+		"bank_card": "4111111111111112",
+		"postcode":  "900000",
+		"plate":     "京I12345",
+	}
+	if fields := forms.Validate("company", bad).Fields(); len(fields) != 6 || fields["id_card"][0] != "身份证号错误, Err:校验码错误" {
+		t.Fatalf("expected every field to fail, got %v", fields)
+	}
+}